@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/rbac"
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// Permissions checked on the state-changing admin routes.
+const (
+	permServerShutdown = "server.shutdown"
+	permServerRestart  = "server.restart"
+	permLogLevel       = "log.level"
+)
+
+const (
+	// adminTokenFile holds the token db create issues to the operator. The
+	// server commands present it by default.
+	adminTokenFile = "admin.token"
+
+	// adminTokensFile maps each operator to a hash of their admin token.
+	adminTokensFile = "admin-tokens.json"
+
+	// adminRole is the role db create assigns to the operator.
+	adminRole = "admin"
+)
+
+// adminTokenPath returns where db create writes the operator's token.
+func adminTokenPath() string {
+	return filepath.Join(store.GetStorePath(), adminTokenFile)
+}
+
+// adminTokensPath returns the file of issued admin token hashes.
+func adminTokensPath() string {
+	return filepath.Join(store.GetStorePath(), adminTokensFile)
+}
+
+// hashAdminToken returns the form of token kept in adminTokensFile.
+func hashAdminToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readAdminTokens returns the issued token hashes by operator. A missing
+// file means no tokens have been issued.
+func readAdminTokens() (map[string]string, error) {
+	data, err := os.ReadFile(adminTokensPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read admin tokens: %w", err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", adminTokensFile, err)
+	}
+	if tokens == nil {
+		tokens = map[string]string{}
+	}
+	return tokens, nil
+}
+
+// writeAdminTokens replaces the issued token hashes.
+func writeAdminTokens(tokens map[string]string) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode admin tokens: %w", err)
+	}
+	return writePrivateFile(adminTokensPath(), append(data, '\n'))
+}
+
+// writePrivateFile writes data to path, readable only by the current user.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of a file it overwrites
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to restrict permissions of %s: %w", path, err)
+	}
+	return nil
+}
+
+// issueAdminToken creates a new admin token for operator, replacing any
+// earlier one, and returns it. Only its hash is stored.
+func issueAdminToken(operator string) (string, error) {
+	if operator == "" {
+		return "", fmt.Errorf("operator name is required")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate admin token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	tokens, err := readAdminTokens()
+	if err != nil {
+		return "", err
+	}
+	tokens[operator] = hashAdminToken(token)
+	if err := writeAdminTokens(tokens); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// revokeAdminToken removes operator's admin token. It reports whether the
+// operator had one.
+func revokeAdminToken(operator string) (bool, error) {
+	tokens, err := readAdminTokens()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := tokens[operator]; !ok {
+		return false, nil
+	}
+	delete(tokens, operator)
+	return true, writeAdminTokens(tokens)
+}
+
+// readTokenFile reads the admin token the server commands present.
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin token (see app rbac token issue): %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// operatorName returns the current OS user, whom db create makes the
+// first administrator.
+func operatorName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// adminGuard protects the state-changing admin routes. A request proves
+// who it acts for with that operator's admin token as a bearer token; the
+// operator's RBAC grants then decide.
+type adminGuard struct {
+	tokens func() (map[string]string, error) // hashes by operator, read per request
+	guard  *rbac.Guard                       // nil in installation mode, where RBAC may not exist
+}
+
+// subject stores the operator owning the request's bearer token with
+// rbac.WithSubject. Other requests pass through without a subject.
+func (a adminGuard) subject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && bearer != "" {
+			tokens, err := a.tokens()
+			if err != nil {
+				logger.FromContext(r.Context(), log).Error("%v", err)
+				writeJSONError(w, http.StatusInternalServerError, "internal_error", "authentication failed")
+				return
+			}
+			hash := []byte(hashAdminToken(bearer))
+			for operator, want := range tokens {
+				if subtle.ConstantTimeCompare(hash, []byte(want)) == 1 {
+					r = r.WithContext(rbac.WithSubject(r.Context(), operator))
+					break
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// require admits authenticated operators granted perm. Without an RBAC
+// store only authentication is checked.
+func (a adminGuard) require(perm string, h http.Handler) http.Handler {
+	if a.guard != nil {
+		return a.subject(a.guard.Permission(perm, h))
+	}
+	return a.subject(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := rbac.SubjectFromContext(r.Context()); !ok {
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized", "authentication required")
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// seedAdminRole creates the admin role with every permission and assigns
+// it to operator, so whoever creates the store can administer the server.
+func seedAdminRole(rs rbac.Store, operator string) error {
+	if err := rs.CreateRole(adminRole, "Full administrative access"); err != nil {
+		return err
+	}
+	if err := rs.Grant(adminRole, rbac.Wildcard); err != nil {
+		return err
+	}
+	if operator == "" {
+		return nil
+	}
+	return rs.Assign(operator, adminRole)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/rbac"
+)
+
+// grantStore serves fixed permission lookups.
+type grantStore struct {
+	rbac.Store
+	perms map[string][]string
+}
+
+func (g grantStore) PermissionsFor(userID string) ([]string, error) {
+	return g.perms[userID], nil
+}
+
+func TestAdminGuard(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	store := grantStore{perms: map[string][]string{
+		"root":     {rbac.Wildcard},
+		"operator": {permServerShutdown},
+		"viewer":   {"status.read"},
+	}}
+	tokens := func() (map[string]string, error) {
+		return map[string]string{
+			"root":     hashAdminToken("root-token"),
+			"operator": hashAdminToken("operator-token"),
+			"viewer":   hashAdminToken("viewer-token"),
+		}, nil
+	}
+	guarded := adminGuard{tokens: tokens, guard: rbac.NewGuard(store, logger.Default)}
+	installation := adminGuard{tokens: tokens}
+
+	tests := []struct {
+		name       string
+		admin      adminGuard
+		perm       string
+		token      string
+		wantStatus int
+		wantError  string
+	}{
+		{"wildcard", guarded, permServerRestart, "root-token", http.StatusOK, ""},
+		{"granted", guarded, permServerShutdown, "operator-token", http.StatusOK, ""},
+		{"missing permission", guarded, permServerShutdown, "viewer-token", http.StatusForbidden, "forbidden"},
+		{"wrong token", guarded, permServerShutdown, "guess", http.StatusUnauthorized, "unauthorized"},
+		{"no token", guarded, permServerShutdown, "", http.StatusUnauthorized, "unauthorized"},
+		{"installation with token", installation, permLogLevel, "viewer-token", http.StatusOK, ""},
+		{"installation without token", installation, permLogLevel, "", http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/shutdown", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			tt.admin.require(tt.perm, ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantError == "" {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] != tt.wantError {
+				t.Errorf("body = %v, %v; want error %q", body, err, tt.wantError)
+			}
+		})
+	}
+
+	t.Run("subject is the token owner", func(t *testing.T) {
+		var got string
+		h := guarded.subject(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = rbac.SubjectFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/admin/shutdown", nil)
+		req.Header.Set("Authorization", "Bearer viewer-token")
+		req.Header.Set("X-Goob-User", "root")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got != "viewer" {
+			t.Errorf("subject = %q, want viewer", got)
+		}
+	})
+}
+
+func TestIssueAdminToken(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	first, err := issueAdminToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := issueAdminToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := readAdminTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens["alice"] != hashAdminToken(second) {
+		t.Errorf("stored %q, want the hash of the latest token", tokens["alice"])
+	}
+	if tokens["alice"] == hashAdminToken(first) {
+		t.Error("reissuing kept the earlier token")
+	}
+
+	if ok, err := revokeAdminToken("alice"); err != nil || !ok {
+		t.Fatalf("revoke = %v, %v; want true", ok, err)
+	}
+	if ok, err := revokeAdminToken("alice"); err != nil || ok {
+		t.Fatalf("second revoke = %v, %v; want false", ok, err)
+	}
+	if tokens, err := readAdminTokens(); err != nil || len(tokens) != 0 {
+		t.Errorf("tokens after revoke = %v, %v", tokens, err)
+	}
+}
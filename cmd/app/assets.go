@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/maloquacious/goobtool/internal/assets"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/public"
+	"github.com/maloquacious/goobtool/templates"
+	"github.com/spf13/cobra"
+)
+
+var assetsForce bool
+
+// publicFS returns the public assets: files in --public take priority over
+// the defaults embedded in the binary.
+func publicFS() fs.FS {
+	return assets.Overlay(publicDir, public.FS)
+}
+
+// logOverlay records whether an asset overlay directory is in use.
+func logOverlay(kind, dir string) {
+	if dir == "" {
+		log.Info("serving embedded %s", kind)
+		return
+	}
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && info.IsDir():
+		logger.With(log, "dir", dir).Info("serving %s with overlay", kind)
+	case errors.Is(err, fs.ErrNotExist):
+		logger.With(log, "dir", dir).Info("%s overlay not found, serving embedded copies", kind)
+	default:
+		logger.With(log, "dir", dir).Warn("%s overlay is not a readable directory, serving embedded copies", kind)
+	}
+}
+
+// newAssetsCmd builds the `assets` command group for the embedded public assets.
+func newAssetsCmd() *cobra.Command {
+	assetsCmd := &cobra.Command{
+		Use:   "assets",
+		Short: "Embedded public asset commands",
+	}
+
+	extractCmd := &cobra.Command{
+		Use:   "extract <dir>",
+		Short: "Write the embedded public assets and templates to a directory for customising",
+		Args:  cobra.ExactArgs(1),
+		Run:   runAssetsExtract,
+	}
+	extractCmd.Flags().BoolVar(&assetsForce, "force", false, "overwrite existing files")
+
+	assetsCmd.AddCommand(extractCmd)
+	return assetsCmd
+}
+
+func runAssetsExtract(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	trees := map[string]fs.FS{"public": public.FS, "templates": templates.FS}
+	paths, err := assets.Extract(dir, trees, assetsForce)
+	if err != nil {
+		log.Error("failed to extract assets: %v", err)
+		os.Exit(1)
+	}
+	for _, p := range paths {
+		fmt.Fprintf(os.Stdout, "wrote %s\n", p)
+	}
+	fmt.Fprintf(os.Stdout, "\nServe them with: %s serve --public %s --templates %s\n",
+		filepath.Base(os.Args[0]), filepath.Join(dir, "public"), filepath.Join(dir, "templates"))
+}
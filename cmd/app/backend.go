@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/maloquacious/goobtool/internal/audit"
+	auditpostgres "github.com/maloquacious/goobtool/internal/audit/postgres"
+	auditsqlite "github.com/maloquacious/goobtool/internal/audit/sqlite"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/rbac"
+	rbacpostgres "github.com/maloquacious/goobtool/internal/rbac/postgres"
+	rbacsqlite "github.com/maloquacious/goobtool/internal/rbac/sqlite"
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/store/postgres"
+	"github.com/maloquacious/goobtool/internal/store/sqlite"
+	"github.com/maloquacious/goobtool/internal/tracing"
+)
+
+// Supported --store-driver values.
+const (
+	driverSQLite   = "sqlite"
+	driverPostgres = "postgres"
+)
+
+// envStoreDSN supplies --store-dsn so credentials stay out of process listings.
+const envStoreDSN = "GOOB_STORE_DSN"
+
+var (
+	storeDriver string
+	storeDSN    string
+)
+
+// appStore is what the server and CLI commands need from a datastore backend.
+type appStore interface {
+	store.Store
+	store.StatsReporter
+	DB() *sql.DB
+	SetTracer(t tracing.Tracer)
+}
+
+// newAppStore returns an unopened store for --store-driver.
+func newAppStore() (appStore, error) {
+	switch storeDriver {
+	case driverSQLite:
+		return sqlite.New(store.GetDBPath(store.GetStorePath()), schemaVersion), nil
+	case driverPostgres:
+		if storeDSN == "" {
+			return nil, fmt.Errorf("--store-dsn (or %s) is required for the postgres driver", envStoreDSN)
+		}
+		return postgres.New(storeDSN, schemaVersion), nil
+	}
+	return nil, fmt.Errorf("unknown store driver %q (want sqlite or postgres)", storeDriver)
+}
+
+// storeLocation names the datastore in logs and CLI output. It never
+// includes credentials from the DSN.
+func storeLocation() string {
+	if storeDriver == driverPostgres {
+		if cfg, err := pgx.ParseConfig(storeDSN); err == nil {
+			return fmt.Sprintf("postgres://%s:%d/%s", cfg.Host, cfg.Port, cfg.Database)
+		}
+		return "postgres"
+	}
+	return store.GetDBPath(store.GetStorePath())
+}
+
+// requireStoreExists exits with guidance when the SQLite file is missing.
+// A PostgreSQL database is created by its administrator, so a missing one
+// shows up as a connection error from Open instead.
+func requireStoreExists() {
+	if storeDriver != driverSQLite {
+		return
+	}
+	storePath := store.GetStorePath()
+	exists, err := store.CheckExists(storePath)
+	if err != nil {
+		log.Error("failed to check datastore: %v", err)
+		os.Exit(1)
+	}
+	if !exists {
+		logger.With(log, "path", storePath).Error("datastore not found")
+		fmt.Fprintln(os.Stderr, "\nDatastore not initialized.")
+		fmt.Fprintf(os.Stderr, "Run: %s db create\n\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
+}
+
+// newRBACStore returns the RBAC store for the selected driver.
+func newRBACStore(db *sql.DB) rbac.Store {
+	if storeDriver == driverPostgres {
+		return rbacpostgres.New(db)
+	}
+	return rbacsqlite.New(db)
+}
+
+// newAuditLog returns the audit log for the selected driver.
+func newAuditLog(db *sql.DB) audit.Log {
+	if storeDriver == driverPostgres {
+		return auditpostgres.New(db)
+	}
+	return auditsqlite.New(db)
+}
+
+// storePools returns the connection pools to report in metrics.
+func storePools(st appStore) map[string]*sql.DB {
+	if s, ok := st.(*sqlite.SQLiteStore); ok {
+		return map[string]*sql.DB{"write": s.DB(), "read": s.ReadDB()}
+	}
+	return map[string]*sql.DB{"main": st.DB()}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/maloquacious/goobtool/internal/render"
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// installNotice is what install.html tells visitors about the datastore.
+// It never includes schema versions; those are on the admin API.
+type installNotice struct {
+	Title   string
+	Summary string
+	Steps   []installStep
+}
+
+// installStep is one "next step"; Command, if set, is shown as code.
+type installStep struct {
+	Text    string
+	Command string
+}
+
+// noticeFor returns the install page message for a datastore state.
+func noticeFor(state store.StoreState) installNotice {
+	app := filepath.Base(os.Args[0])
+	switch state {
+	case store.StateUninitialized:
+		return installNotice{
+			Title:   "Database initialization required",
+			Summary: "The datastore exists but its schema has not been created.",
+			Steps: []installStep{
+				{Text: "Create the schema:", Command: app + " db create"},
+			},
+		}
+	case store.StateNeedsUpgrade:
+		return installNotice{
+			Title:   "Database upgrade required",
+			Summary: "The datastore schema is older than this version of the application.",
+			Steps: []installStep{
+				{Text: "Back up the datastore"},
+				{Text: "Apply the pending migrations:", Command: app + " db upgrade"},
+			},
+		}
+	case store.StateTooNew:
+		return installNotice{
+			Title:   "Application upgrade required",
+			Summary: "The datastore schema is newer than this version of the application. The application will not start against it, to avoid damaging data written by a newer release.",
+			Steps: []installStep{
+				{Text: "Install the release that last upgraded the datastore, or a newer one"},
+			},
+		}
+	}
+	return installNotice{
+		Title:   "Unrecognized schema version",
+		Summary: "The datastore schema version could not be compared with the version this application expects.",
+		Steps: []installStep{
+			{Text: "Check the server logs for the versions involved"},
+		},
+	}
+}
+
+// installHandler renders the install page with the notice for state.
+func installHandler(pages *render.Renderer, state store.StoreState) http.Handler {
+	return pageHandler(pages, "install", noticeFor(state))
+}
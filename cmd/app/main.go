@@ -13,17 +13,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/maloquacious/goobtool/internal/accesslog"
+	"github.com/maloquacious/goobtool/internal/audit"
+	"github.com/maloquacious/goobtool/internal/certs"
+	"github.com/maloquacious/goobtool/internal/csrf"
+	"github.com/maloquacious/goobtool/internal/health"
 	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/metrics"
+	"github.com/maloquacious/goobtool/internal/rbac"
+	"github.com/maloquacious/goobtool/internal/render"
+	"github.com/maloquacious/goobtool/internal/requestid"
+	"github.com/maloquacious/goobtool/internal/secheaders"
+	"github.com/maloquacious/goobtool/internal/static"
 	"github.com/maloquacious/goobtool/internal/store"
-	"github.com/maloquacious/goobtool/internal/store/sqlite"
+	"github.com/maloquacious/goobtool/internal/tracing"
 	"github.com/maloquacious/semver"
 	"github.com/spf13/cobra"
 )
 
 var (
-	version       = semver.Version{Minor: 1, Patch: 3, PreRelease: "alpha", Build: semver.Commit()}
+	version       = semver.Version{Minor: 1, Patch: 3, PreRelease: "alpha"}
 	schemaVersion = "0.1"
-	buildDate     = ""
+	buildDate     = "" // set with -ldflags "-X main.buildDate=..." to record the build time
+	startTime     = time.Now()
 )
 
 var (
@@ -33,7 +45,21 @@ var (
 	shutdownTO time.Duration
 	exitAfter  time.Duration
 	publicDir  string
+	logFormat  string
+	logLevel   string
 	log        logger.Logger = logger.Default
+
+	accessLogFormat      string
+	adminAccessLogFormat string
+	accessLogRedact      []string
+
+	traceExporter string
+	otlpEndpoint  string
+	tracer        tracing.Tracer = tracing.Noop
+
+	readyTimeout   time.Duration
+	readyMinDiskMB int64
+	readyMaxWALMB  int64
 )
 
 func main() {
@@ -44,7 +70,12 @@ func main() {
 
 	// Global flags
 	rootCmd.PersistentFlags().DurationVar(&shutdownTO, "shutdown-timeout", 15*time.Second, "graceful shutdown timeout")
-	rootCmd.PersistentFlags().StringVar(&publicDir, "public", "public", "directory for static public assets")
+	rootCmd.PersistentFlags().StringVar(&publicDir, "public", "public", "directory whose files override the embedded public assets (ignored if missing)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log output format (text or json)")
+	rootCmd.PersistentFlags().StringVar(&storeDriver, "store-driver", driverSQLite, "datastore backend (sqlite or postgres)")
+	rootCmd.PersistentFlags().StringVar(&storeDSN, "store-dsn", os.Getenv(envStoreDSN), "PostgreSQL connection string for --store-driver=postgres (default $"+envStoreDSN+")")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "minimum log level (debug, info, warn, error)")
+	rootCmd.PersistentPreRunE = configureLogger
 
 	// serve command
 	serveCmd := &cobra.Command{
@@ -55,7 +86,25 @@ func main() {
 	serveCmd.Flags().IntVar(&port, "port", 8080, "public HTTP port (HTML/HTMX)")
 	serveCmd.Flags().IntVar(&adminPort, "admin-port", 8383, "admin HTTP port (JSON, loopback only)")
 	serveCmd.Flags().StringVar(&adminHost, "admin-host", "127.0.0.1", "admin host (127.0.0.1 or ::1, loopback only)")
+	serveCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "PEM certificate file; serves the public port over HTTPS (reloaded on SIGHUP or change)")
+	serveCmd.Flags().StringVar(&tlsKey, "tls-key", "", "PEM private key file for --tls-cert")
+	serveCmd.Flags().DurationVar(&tlsWatchInterval, "tls-watch-interval", 30*time.Second, "how often to check the certificate files for changes (0 to only reload on SIGHUP)")
+	serveCmd.Flags().IntVar(&httpRedirectPort, "http-redirect-port", 0, "optional plain HTTP port that redirects to the HTTPS public port (requires --tls-cert)")
+	serveCmd.Flags().StringVar(&cspPolicy, "csp", secheaders.DefaultPolicy, "Content-Security-Policy for the public port; "+secheaders.NonceSource+" becomes the per-request nonce (empty to disable)")
+	serveCmd.Flags().BoolVar(&cspReportOnly, "csp-report-only", false, "only report CSP violations to "+cspReportPath+" instead of blocking them")
+	serveCmd.Flags().DurationVar(&hstsMaxAge, "hsts-max-age", 365*24*time.Hour, "Strict-Transport-Security max-age sent over HTTPS (0 to disable)")
+	serveCmd.Flags().StringVar(&frameAncestors, "frame-ancestors", secheaders.DefaultFrameAncestors, "CSP frame-ancestors sources allowed to frame public pages")
+	serveCmd.Flags().BoolVar(&devMode, "dev", false, "development mode: reload templates and public assets from disk on every request")
+	serveCmd.Flags().StringVar(&templatesDir, "templates", "templates", "directory whose templates override the embedded ones (ignored if missing)")
 	serveCmd.Flags().DurationVar(&exitAfter, "exit-after", 0, "optional runtime; if set, server exits after this duration (testing)")
+	serveCmd.Flags().StringVar(&accessLogFormat, "access-log", "combined", "public access log format (off, common, combined, json)")
+	serveCmd.Flags().StringVar(&adminAccessLogFormat, "admin-access-log", "json", "admin access log format (off, common, combined, json)")
+	serveCmd.Flags().StringVar(&traceExporter, "trace-exporter", "none", "trace exporter (none or otlp)")
+	serveCmd.Flags().StringVar(&otlpEndpoint, "otlp-endpoint", tracing.DefaultOTLPEndpoint, "OTLP/HTTP traces endpoint for --trace-exporter=otlp")
+	serveCmd.Flags().DurationVar(&readyTimeout, "ready-timeout", health.DefaultTimeout, "timeout for each readiness probe")
+	serveCmd.Flags().Int64Var(&readyMinDiskMB, "ready-min-disk-mb", 64, "not ready when the store directory has less free space (MiB)")
+	serveCmd.Flags().Int64Var(&readyMaxWALMB, "ready-max-wal-mb", 512, "not ready when the SQLite WAL grows beyond this size (MiB)")
+	serveCmd.Flags().StringSliceVar(&accessLogRedact, "access-log-redact", accesslog.DefaultRedact, "query parameters whose values are redacted in access logs")
 
 	// db command group
 	dbCmd := &cobra.Command{
@@ -80,98 +129,157 @@ func main() {
 	}
 
 	dbCmd.AddCommand(dbCreateCmd, dbUpgradeCmd, dbVerifyCmd)
-	rootCmd.AddCommand(serveCmd, dbCmd)
+	rootCmd.AddCommand(serveCmd, dbCmd, newServerCmd(), newRBACCmd(), newAuditCmd(), newAssetsCmd(), newCertCmd(), newVersionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
+// configureLogger replaces the default logger with a structured one
+// built from --log-format and --log-level.
+func configureLogger(cmd *cobra.Command, args []string) error {
+	format, err := logger.ParseFormat(logFormat)
+	if err != nil {
+		return err
+	}
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	log = logger.NewSlogLogger(os.Stdout, format, level)
+	logger.Default = log
+	return nil
+}
+
 // runServe starts both the public (HTML) and admin (JSON) servers with graceful shutdown.
 func runServe(cmd *cobra.Command, args []string) {
-	log.Info("starting Goobergine server version=%s schema=%s", version.String(), schemaVersion)
+	logger.With(log, "version", buildInfo().Version, "schema", schemaVersion).Info("starting Goobergine server")
+
+	// Cancelled on SIGINT so that slow startup queries can be interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	// Tracing must be ready before the store opens so its spans are exported
+	switch traceExporter {
+	case "none":
+	case "otlp":
+		tracer = tracing.NewTracer(tracing.NewOTLPExporter(otlpEndpoint, "goobtool"), tracing.Options{Logger: log})
+		logger.With(log, "endpoint", otlpEndpoint).Info("tracing enabled (otlp)")
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTO)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				log.Warn("tracing shutdown: %v", err)
+			}
+		}()
+	default:
+		logger.With(log, "exporter", traceExporter).Error("unknown trace exporter (want none or otlp)")
+		os.Exit(1)
+	}
+
+	// Certificates, assets and templates are checked before anything is served
+	tlsCerts := setupTLS(ctx)
+	files := newStaticHandler()
+	pages := newPageRenderer(files)
 
 	// Check datastore existence and state
 	// NOTE: os.Exit is safe here - we're in initialization phase before any servers start.
 	// If startup sequence changes, verify no resources need cleanup before these exits.
-	storePath := store.GetStorePath()
-	dbPath := store.GetDBPath(storePath)
-	exists, err := store.CheckExists(storePath)
-	if err != nil {
-		log.Error("failed to check datastore: %v", err)
-		os.Exit(1)
-	}
+	requireStoreExists()
 
-	if !exists {
-		log.Error("datastore not found at path=%s", storePath)
-		fmt.Fprintln(os.Stderr, "\nDatastore not initialized.")
-		fmt.Fprintf(os.Stderr, "Run: %s db create\n\n", filepath.Base(os.Args[0]))
+	// Open database and check state
+	st, err := newAppStore()
+	if err != nil {
+		log.Error("%v", err)
 		os.Exit(1)
 	}
-
-	// Open database and check state
-	st := sqlite.New(dbPath, schemaVersion)
-	if err := st.Open(); err != nil {
+	st.SetTracer(tracer)
+	if err := st.Open(ctx); err != nil {
 		log.Error("failed to open datastore: %v", err)
 		os.Exit(1)
 	}
 	defer st.Close()
 
-	state, err := st.CheckState()
+	state, err := st.CheckState(ctx)
 	if err != nil {
 		log.Error("failed to check datastore state: %v", err)
 		os.Exit(1)
 	}
 
-	// Handle uninitialized or mismatched store
-	if state == store.StateUninitialized {
+	// Anything but a ready store gets the installation app instead
+	switch state {
+	case store.StateReady:
+	case store.StateUninitialized:
 		log.Warn("datastore uninitialized (missing schema_migrations table)")
-		serveInstallationApp(port, adminPort, adminHost, exitAfter, shutdownTO)
+		serveInstallationApp(ctx, st, files, pages, tlsCerts, port, adminPort, adminHost, exitAfter, shutdownTO, state, "")
+		return
+	default:
+		actualVersion, _ := st.GetSchemaVersion(ctx)
+		l := logger.With(log, "expected", schemaVersion, "actual", actualVersion)
+		switch state {
+		case store.StateNeedsUpgrade:
+			l.Warn("datastore schema is older than this binary; run db upgrade")
+		case store.StateTooNew:
+			l.Error("datastore schema is newer than this binary; refusing to start the application")
+		default:
+			l.Warn("datastore version mismatch")
+		}
+		serveInstallationApp(ctx, st, files, pages, tlsCerts, port, adminPort, adminHost, exitAfter, shutdownTO, state, actualVersion)
 		return
 	}
 
-	if state == store.StateVersionMismatch {
-		actualVersion, _ := st.GetSchemaVersion()
-		log.Warn("datastore version mismatch: expected=%s actual=%s", schemaVersion, actualVersion)
-		serveInstallationApp(port, adminPort, adminHost, exitAfter, shutdownTO)
-		return
+	// Ensure subsystem tables exist (idempotent)
+	if err := newRBACStore(st.DB()).InitSchema(); err != nil {
+		log.Error("failed to initialize rbac schema: %v", err)
+		st.Close()
+		os.Exit(1)
+	}
+	auditLog := newAuditLog(st.DB())
+	if err := auditLog.InitSchema(); err != nil {
+		log.Error("failed to initialize audit schema: %v", err)
+		st.Close()
+		os.Exit(1)
 	}
 
-	log.Info("datastore ready path=%s schema=%s", dbPath, schemaVersion)
+	logger.With(log, "driver", storeDriver, "location", storeLocation(), "schema", schemaVersion).Info("datastore ready")
 
 	publicMux := http.NewServeMux()
 	adminMux := http.NewServeMux()
+	publicCSRF, adminCSRF := newCSRF()
+	admin := adminGuard{tokens: readAdminTokens, guard: rbac.NewGuard(newRBACStore(st.DB()), log)}
+
+	reg := metrics.NewRegistry()
+	reg.Register(
+		serverCollector("running", schemaVersion),
+		metrics.UptimeCollector(startTime),
+		metrics.RuntimeCollector(),
+		metrics.DBStatsCollector(storePools(st)),
+	)
 
 	// --- Public routes (HTML/HTMX) ---
-	publicMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Serve /public (index.html) by default
-		fp := filepath.Join(publicDir, "index.html")
-		http.ServeFile(w, r, fp)
-	})
+	// Everything not routed below gets the home page
+	publicMux.Handle("/", pageHandler(pages, "index", nil))
 
 	publicMux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
-	publicMux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		// Store is initialized and ready (we checked state earlier)
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("READY"))
-	})
+	// Readiness aggregates the probes; details are only on the admin listener
+	probes := newHealthRegistry(st)
+	publicMux.Handle("/ready", probes.ReadyHandler())
 
-	publicMux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"appVersion":    version.String(),
-			"schemaVersion": schemaVersion,
-			"goVersion":     "go1.25.2",
-			"buildDate":     buildDate,
-		})
-	})
+	publicMux.Handle("/version", versionHandler())
+
+	// CSRF token for HTMX/Alpine clients
+	publicMux.Handle("/api/auth/csrf", publicCSRF.Handler())
 
-	// Static under /public/* (maps to ./public)
-	publicMux.Handle("/public/", http.StripPrefix("/public/", http.FileServer(http.Dir(publicDir))))
+	// Browsers post CSP violations here (exempt from CSRF)
+	publicMux.Handle(cspReportPath, secheaders.ReportHandler(log))
+
+	// Static under /public/* (--public overlaid on the embedded assets)
+	publicMux.Handle("/public/", files)
 
 	// --- Admin routes (JSON-only, loopback only) ---
 	adminMux.Handle("/admin/echo", jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -191,20 +299,25 @@ func runServe(cmd *cobra.Command, args []string) {
 		_ = json.NewEncoder(w).Encode(map[string]string{"echo": payload.Echo})
 	})))
 
-	adminMux.Handle("/admin/status", jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		now := time.Now().UTC().Format(time.RFC3339)
-		resp := map[string]string{
-			"version":       version.String(),
-			"schemaVersion": schemaVersion,
-			"buildDate":     buildDate,
-			"time":          now,
-			"mode":          "running",
-		}
-		_ = json.NewEncoder(w).Encode(resp)
-	})))
+	info := &serverInfo{mode: "running", st: st, health: probes}
+	adminMux.Handle("/admin/status", jsonOnly(statusHandler(info)))
+	adminMux.Handle("GET /admin/health", jsonOnly(probes.DetailHandler()))
+
+	adminMux.Handle("/admin/csrf", jsonOnly(adminCSRF.Handler()))
+	adminMux.Handle("GET /admin/log/level", jsonOnly(logLevelHandler(auditLog)))
+	adminMux.Handle("POST /admin/log/level", admin.require(permLogLevel, jsonOnly(logLevelHandler(auditLog))))
+
+	// Prometheus scrape endpoint (text format; exempt from the JSON-only rule)
+	adminMux.Handle("GET /admin/metrics", reg.Handler())
 
-	adminMux.Handle("/admin/shutdown", jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	adminMux.Handle("/admin/shutdown", postOnly(admin.require(permServerShutdown, jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO: coordinate shutdown via context cancellation signal channel
+		logger.FromContext(r.Context(), log).Info("admin shutdown requested")
+		if err := recordAdminAudit(auditLog, r, audit.ActionServerShutdown, "", nil); err != nil {
+			logger.FromContext(r.Context(), log).Error("failed to record audit entry: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "audit_failed", "failed to record audit entry")
+			return
+		}
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "shutting down"})
 		go func() {
 			// give the response a moment to flush
@@ -212,21 +325,30 @@ func runServe(cmd *cobra.Command, args []string) {
 			proc, _ := os.FindProcess(os.Getpid())
 			_ = proc.Signal(os.Interrupt)
 		}()
-	})))
+	})))))
 
-	adminMux.Handle("/admin/restart", jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	adminMux.Handle("/admin/restart", postOnly(admin.require(permServerRestart, jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO: implement real restart (requires external supervisor). For now, exit 0.
+		logger.FromContext(r.Context(), log).Info("admin restart requested")
+		if err := recordAdminAudit(auditLog, r, audit.ActionServerRestart, "", nil); err != nil {
+			logger.FromContext(r.Context(), log).Error("failed to record audit entry: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "audit_failed", "failed to record audit entry")
+			return
+		}
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "restarting"})
 		go func() {
 			time.Sleep(200 * time.Millisecond)
 			os.Exit(0)
 		}()
-	})))
+	})))))
 
 	// HTTP servers
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 	publicSrv := &http.Server{
-		Addr:    net.JoinHostPort("", fmt.Sprintf("%d", port)),
-		Handler: publicMux,
+		Addr:        net.JoinHostPort("", fmt.Sprintf("%d", port)),
+		Handler:     newSecurityHeaders()(serverHandler("public", publicMux, reg, publicCSRF, accessLogFormat)),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
 	}
 
 	// Validate admin host is loopback before binding
@@ -234,7 +356,7 @@ func runServe(cmd *cobra.Command, args []string) {
 	// If startup sequence changes (e.g., open DB earlier), verify no resources need cleanup.
 	adminIP := net.ParseIP(adminHost)
 	if adminIP == nil || !adminIP.IsLoopback() {
-		log.Error("admin host must be loopback (127.0.0.1 or ::1), got: %s", adminHost)
+		logger.With(log, "host", adminHost).Error("admin host must be loopback (127.0.0.1 or ::1)")
 		os.Exit(1)
 	}
 
@@ -249,32 +371,61 @@ func runServe(cmd *cobra.Command, args []string) {
 	// Verify loopback-only binding (defense in depth)
 	if addr, ok := adminListener.Addr().(*net.TCPAddr); ok {
 		if !addr.IP.IsLoopback() {
-			log.Error("admin listener bound to non-loopback address: %s", addr.IP)
+			logger.With(log, "addr", addr.IP.String()).Error("admin listener bound to non-loopback address")
 			adminListener.Close()
 			os.Exit(1)
 		}
-		log.Info("admin listener verified on loopback: %s", addr.String())
+		logger.With(log, "addr", addr.String()).Info("admin listener verified on loopback")
 	}
 
 	adminSrv := &http.Server{
-		Handler: adminMux,
+		Handler:     serverHandler("admin", adminMux, reg, adminCSRF, adminAccessLogFormat),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
 	}
 
-	// Run servers
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
-	defer stop()
+	// Bind public before serving so bind errors surface at startup
+	publicListener, err := net.Listen("tcp", publicSrv.Addr)
+	if err != nil {
+		log.Error("public listener bind failed: %v", err)
+		adminListener.Close()
+		os.Exit(1)
+	}
+	redirectSrv, redirectListener, err := listenRedirect(port)
+	if err != nil {
+		log.Error("%v", err)
+		publicListener.Close()
+		adminListener.Close()
+		os.Exit(1)
+	}
+	info.listeners = map[string]string{
+		"public": publicListener.Addr().String(),
+		"admin":  adminListener.Addr().String(),
+	}
+	if redirectListener != nil {
+		info.listeners["redirect"] = redirectListener.Addr().String()
+	}
 
-	errCh := make(chan error, 2)
+	// Run servers
+	errCh := make(chan error, 3)
 
 	go func() {
-		log.Info("public server listening on port=%d", port)
-		if err := publicSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.With(log, "addr", publicListener.Addr().String(), "tls", tlsCerts != nil).Info("public server listening")
+		if err := servePublic(publicSrv, publicListener, tlsCerts); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- fmt.Errorf("public server error: %w", err)
 		}
 	}()
 
+	if redirectListener != nil {
+		go func() {
+			logger.With(log, "addr", redirectListener.Addr().String()).Info("redirecting plain HTTP to HTTPS")
+			if err := redirectSrv.Serve(redirectListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("redirect server error: %w", err)
+			}
+		}()
+	}
+
 	go func() {
-		log.Info("admin server listening on 127.0.0.1:%d (JSON-only)", adminPort)
+		logger.With(log, "addr", adminListener.Addr().String()).Info("admin server listening (JSON-only)")
 		if err := adminSrv.Serve(adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- fmt.Errorf("admin server error: %w", err)
 		}
@@ -283,7 +434,7 @@ func runServe(cmd *cobra.Command, args []string) {
 	// Optional run timer
 	if exitAfter > 0 {
 		go func() {
-			log.Info("exit-after timer set duration=%s", exitAfter)
+			logger.With(log, "duration", exitAfter.String()).Info("exit-after timer set")
 			time.Sleep(exitAfter)
 			proc, _ := os.FindProcess(os.Getpid())
 			_ = proc.Signal(os.Interrupt)
@@ -297,27 +448,32 @@ func runServe(cmd *cobra.Command, args []string) {
 		log.Error("server error: %v", err)
 	}
 
-	log.Info("initiating graceful shutdown timeout=%s", shutdownTO)
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTO)
-	defer cancel()
-
-	_ = publicSrv.Shutdown(shutdownCtx)
-	_ = adminSrv.Shutdown(shutdownCtx)
-	log.Info("shutdown complete")
+	shutdownServers(cancelRequests, publicSrv, adminSrv, redirectSrv)
 }
 
-// serveInstallationApp serves a minimal installation/maintenance page.
-func serveInstallationApp(port, adminPort int, adminHost string, exitAfter, shutdownTO time.Duration) {
+// serveInstallationApp serves a minimal installation/maintenance page for
+// the datastore state until ctx is done. actualSchema is the version found
+// in the datastore, if any.
+func serveInstallationApp(ctx context.Context, st appStore, files *static.Handler, pages *render.Renderer, tlsCerts *certs.Reloader, port, adminPort int, adminHost string, exitAfter, shutdownTO time.Duration, state store.StoreState, actualSchema string) {
 	log.Info("serving installation app (datastore requires attention)")
 
 	publicMux := http.NewServeMux()
 	adminMux := http.NewServeMux()
-
-	// Serve installation page
-	publicMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fp := filepath.Join(publicDir, "install.html")
-		http.ServeFile(w, r, fp)
-	})
+	publicCSRF, adminCSRF := newCSRF()
+	// RBAC tables may not exist yet, so any issued admin token is accepted
+	admin := adminGuard{tokens: readAdminTokens}
+
+	reg := metrics.NewRegistry()
+	reg.Register(
+		serverCollector("installation", actualSchema),
+		metrics.UptimeCollector(startTime),
+		metrics.RuntimeCollector(),
+	)
+
+	// Serve installation page and the static assets it uses
+	publicMux.Handle("/", installHandler(pages, state))
+	publicMux.Handle("/public/", files)
+	publicMux.Handle(cspReportPath, secheaders.ReportHandler(log))
 
 	// Health endpoints
 	publicMux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
@@ -332,37 +488,31 @@ func serveInstallationApp(port, adminPort int, adminHost string, exitAfter, shut
 	})
 
 	// Version endpoint
-	publicMux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"appVersion":    version.String(),
-			"schemaVersion": schemaVersion,
-			"goVersion":     "go1.25.2",
-			"buildDate":     buildDate,
-		})
-	})
+	publicMux.Handle("/version", versionHandler())
 
-	// Admin status endpoint (minimal)
-	adminMux.Handle("/admin/status", jsonOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		now := time.Now().UTC().Format(time.RFC3339)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"version":       version.String(),
-			"schemaVersion": schemaVersion,
-			"buildDate":     buildDate,
-			"time":          now,
-			"mode":          "installation",
-		})
-	})))
+	adminMux.Handle("/admin/csrf", jsonOnly(adminCSRF.Handler()))
+	adminMux.Handle("GET /admin/log/level", jsonOnly(logLevelHandler(nil)))
+	adminMux.Handle("POST /admin/log/level", admin.require(permLogLevel, jsonOnly(logLevelHandler(nil))))
+	adminMux.Handle("GET /admin/metrics", reg.Handler())
+
+	// Probes still run for the admin detail view; public /ready stays NOT_READY
+	probes := newHealthRegistry(st)
+	info := &serverInfo{mode: "installation", st: st, health: probes}
+	adminMux.Handle("/admin/status", jsonOnly(statusHandler(info)))
+	adminMux.Handle("GET /admin/health", jsonOnly(probes.DetailHandler()))
 
 	// Setup servers (same as regular runServe)
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 	publicSrv := &http.Server{
-		Addr:    net.JoinHostPort("", fmt.Sprintf("%d", port)),
-		Handler: publicMux,
+		Addr:        net.JoinHostPort("", fmt.Sprintf("%d", port)),
+		Handler:     newSecurityHeaders()(serverHandler("public", publicMux, reg, publicCSRF, accessLogFormat)),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
 	}
 
 	adminIP := net.ParseIP(adminHost)
 	if adminIP == nil || !adminIP.IsLoopback() {
-		log.Error("admin host must be loopback (127.0.0.1 or ::1), got: %s", adminHost)
+		logger.With(log, "host", adminHost).Error("admin host must be loopback (127.0.0.1 or ::1)")
 		os.Exit(1)
 	}
 
@@ -375,31 +525,60 @@ func serveInstallationApp(port, adminPort int, adminHost string, exitAfter, shut
 
 	if addr, ok := adminListener.Addr().(*net.TCPAddr); ok {
 		if !addr.IP.IsLoopback() {
-			log.Error("admin listener bound to non-loopback address: %s", addr.IP)
+			logger.With(log, "addr", addr.IP.String()).Error("admin listener bound to non-loopback address")
 			adminListener.Close()
 			os.Exit(1)
 		}
-		log.Info("admin listener verified on loopback: %s", addr.String())
+		logger.With(log, "addr", addr.String()).Info("admin listener verified on loopback")
 	}
 
 	adminSrv := &http.Server{
-		Handler: adminMux,
+		Handler:     serverHandler("admin", adminMux, reg, adminCSRF, adminAccessLogFormat),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
-	defer stop()
+	// Bind public before serving so bind errors surface at startup
+	publicListener, err := net.Listen("tcp", publicSrv.Addr)
+	if err != nil {
+		log.Error("public listener bind failed: %v", err)
+		adminListener.Close()
+		os.Exit(1)
+	}
+	redirectSrv, redirectListener, err := listenRedirect(port)
+	if err != nil {
+		log.Error("%v", err)
+		publicListener.Close()
+		adminListener.Close()
+		os.Exit(1)
+	}
+	info.listeners = map[string]string{
+		"public": publicListener.Addr().String(),
+		"admin":  adminListener.Addr().String(),
+	}
+	if redirectListener != nil {
+		info.listeners["redirect"] = redirectListener.Addr().String()
+	}
 
-	errCh := make(chan error, 2)
+	errCh := make(chan error, 3)
 
 	go func() {
-		log.Info("public server listening on port=%d (installation mode)", port)
-		if err := publicSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.With(log, "addr", publicListener.Addr().String(), "mode", "installation", "tls", tlsCerts != nil).Info("public server listening")
+		if err := servePublic(publicSrv, publicListener, tlsCerts); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- fmt.Errorf("public server error: %w", err)
 		}
 	}()
 
+	if redirectListener != nil {
+		go func() {
+			logger.With(log, "addr", redirectListener.Addr().String()).Info("redirecting plain HTTP to HTTPS")
+			if err := redirectSrv.Serve(redirectListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("redirect server error: %w", err)
+			}
+		}()
+	}
+
 	go func() {
-		log.Info("admin server listening on 127.0.0.1:%d (JSON-only)", adminPort)
+		logger.With(log, "addr", adminListener.Addr().String()).Info("admin server listening (JSON-only)")
 		if err := adminSrv.Serve(adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- fmt.Errorf("admin server error: %w", err)
 		}
@@ -407,7 +586,7 @@ func serveInstallationApp(port, adminPort int, adminHost string, exitAfter, shut
 
 	if exitAfter > 0 {
 		go func() {
-			log.Info("exit-after timer set duration=%s", exitAfter)
+			logger.With(log, "duration", exitAfter.String()).Info("exit-after timer set")
 			time.Sleep(exitAfter)
 			proc, _ := os.FindProcess(os.Getpid())
 			_ = proc.Signal(os.Interrupt)
@@ -421,15 +600,150 @@ func serveInstallationApp(port, adminPort int, adminHost string, exitAfter, shut
 		log.Error("server error: %v", err)
 	}
 
-	log.Info("initiating graceful shutdown timeout=%s", shutdownTO)
+	shutdownServers(cancelRequests, publicSrv, adminSrv, redirectSrv)
+}
+
+// shutdownServers stops srvs gracefully within shutdownTO, skipping nils. Requests still
+// running when the timeout expires have their contexts cancelled through
+// cancelRequests, which interrupts any store queries they are waiting on.
+func shutdownServers(cancelRequests context.CancelFunc, srvs ...*http.Server) {
+	logger.With(log, "timeout", shutdownTO.String()).Info("initiating graceful shutdown")
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTO)
 	defer cancel()
 
-	_ = publicSrv.Shutdown(shutdownCtx)
-	_ = adminSrv.Shutdown(shutdownCtx)
+	for _, srv := range srvs {
+		if srv == nil {
+			continue
+		}
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Warn("graceful shutdown timed out; cancelling in-flight requests")
+			cancelRequests()
+			_ = srv.Close()
+		}
+	}
 	log.Info("shutdown complete")
 }
 
+// logLevelHandler reports (GET) or changes (POST {"level": "debug"}) the
+// minimum log level at runtime. Changes are recorded in al when it is not nil.
+func logLevelHandler(al audit.Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lv, ok := log.(logger.Leveler)
+		if !ok {
+			writeJSONError(w, http.StatusNotImplemented, "not_implemented", "logger does not support runtime levels")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var payload struct {
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
+				return
+			}
+			level, err := logger.ParseLevel(payload.Level)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			if err := recordAdminAudit(al, r, audit.ActionLogLevel, "", map[string]any{"from": lv.Level().String(), "to": level.String()}); err != nil {
+				logger.FromContext(r.Context(), log).Error("failed to record audit entry: %v", err)
+				writeJSONError(w, http.StatusInternalServerError, "audit_failed", "failed to record audit entry")
+				return
+			}
+			lv.SetLevel(level)
+			logger.With(logger.FromContext(r.Context(), log), "level", level.String()).Info("log level changed")
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method must be GET or POST")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"level": strings.ToLower(lv.Level().String())})
+	})
+}
+
+// serverCollector reports the server mode, schema versions and build info.
+func serverCollector(mode, actualSchema string) metrics.Collector {
+	return func(w *metrics.Writer) {
+		for _, m := range []string{"running", "installation", "maintenance"} {
+			v := 0.0
+			if m == mode {
+				v = 1
+			}
+			w.Gauge("goob_server_mode", "Current server mode (1 for the active mode).", v, "mode", m)
+		}
+		w.Gauge("goob_schema_info", "Schema version expected by the binary and found in the datastore.", 1, "expected", schemaVersion, "actual", actualSchema)
+		info := buildInfo()
+		w.Gauge("goob_build_info", "Build information.", 1, "version", info.Version, "go_version", info.GoVersion, "revision", info.Revision, "build_date", info.BuildTime, "commit_date", info.CommitTime)
+	}
+}
+
+// serverHandler assembles the middleware chain shared by both listeners,
+// outermost first: request ID, tracing, access log, metrics, CSRF, mux.
+func serverHandler(listener string, mux *http.ServeMux, reg *metrics.Registry, protect csrf.CSRFMiddleware, accessFormat string) http.Handler {
+	var h http.Handler = protect.Protect(mux)
+	h = reg.Middleware(listener, mux)(h)
+	h = newAccessLog(listener, accessFormat)(h)
+	h = tracing.Middleware(tracer, listener, mux)(h)
+	return requestid.Middleware(h)
+}
+
+// newAccessLog builds the access log middleware for a listener.
+// NOTE: os.Exit is safe here - it is only called during initialization.
+func newAccessLog(listener, format string) func(http.Handler) http.Handler {
+	f, err := accesslog.ParseFormat(format)
+	if err != nil {
+		logger.With(log, "listener", listener).Error("invalid access log format: %v", err)
+		os.Exit(1)
+	}
+	return accesslog.Middleware(accesslog.Options{
+		Listener: listener,
+		Format:   f,
+		Redact:   accessLogRedact,
+		Logger:   log,
+	})
+}
+
+// newCSRF creates the CSRF middleware for the public and admin listeners.
+// They use distinct cookies because browsers share cookies across ports.
+// SessionID is left unset until a session manager exists, so tokens are
+// not bound to a user; see SECURITY_CONSIDERATIONS.md.
+// NOTE: os.Exit is safe here - it is only called during initialization.
+func newCSRF() (public, admin *csrf.DoubleSubmit) {
+	public, err := csrf.New(csrf.Options{
+		ErrorHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden: missing or invalid CSRF token", http.StatusForbidden)
+		}),
+		// browsers send CSP reports without a token
+		Exempt: func(r *http.Request) bool { return r.URL.Path == cspReportPath },
+	})
+	if err != nil {
+		log.Error("failed to initialize public csrf middleware: %v", err)
+		os.Exit(1)
+	}
+	admin, err = csrf.New(csrf.Options{CookieName: "goob_admin_csrf"})
+	if err != nil {
+		log.Error("failed to initialize admin csrf middleware: %v", err)
+		os.Exit(1)
+	}
+	return public, admin
+}
+
+// postOnly rejects requests to state-changing admin routes that are not POST,
+// so they cannot be triggered without passing CSRF validation.
+func postOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method must be POST")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // jsonOnly enforces JSON-only contract for admin routes.
 func jsonOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -459,49 +773,146 @@ func writeJSONError(w http.ResponseWriter, status int, code, msg string) {
 // --- DB command implementations ---
 
 func runDBCreate(cmd *cobra.Command, args []string) {
-	log.Info("creating datastore schema=%s", schemaVersion)
+	logger.With(log, "schema", schemaVersion).Info("creating datastore")
 
-	storePath := store.GetStorePath()
-	dbPath := store.GetDBPath(storePath)
+	location := storeLocation()
 
-	// Check if database already exists
-	exists, err := store.CheckExists(storePath)
-	if err != nil {
-		log.Error("failed to check datastore: %v", err)
-		os.Exit(1)
+	// A PostgreSQL database always exists before create; a SQLite file may
+	// have been created empty, or left behind without a schema
+	existed := true
+	if storeDriver == driverSQLite {
+		exists, err := store.CheckExists(store.GetStorePath())
+		if err != nil {
+			log.Error("failed to check datastore: %v", err)
+			os.Exit(1)
+		}
+		existed = exists
 	}
 
-	if exists {
-		log.Error("datastore already exists at path=%s", dbPath)
-		fmt.Fprintln(os.Stderr, "\nDatastore already exists.")
-		fmt.Fprintf(os.Stderr, "Path: %s\n\n", dbPath)
+	// Create and initialize the database
+	ctx := cmd.Context()
+	st, err := newAppStore()
+	if err != nil {
+		log.Error("%v", err)
 		os.Exit(1)
 	}
-
-	// Create and initialize the database
-	st := sqlite.New(dbPath, schemaVersion)
-	if err := st.Open(); err != nil {
+	if err := st.Open(ctx); err != nil {
 		log.Error("failed to open database: %v", err)
 		os.Exit(1)
 	}
 	defer st.Close()
 
-	if err := st.InitSchema(schemaVersion); err != nil {
+	// refuse to touch an existing datastore that already has a schema
+	if existed {
+		state, err := st.CheckState(ctx)
+		if err != nil {
+			log.Error("failed to check datastore state: %v", err)
+			st.Close()
+			os.Exit(1)
+		}
+		if state != store.StateUninitialized {
+			logger.With(log, "location", location).Error("datastore already initialized")
+			fmt.Fprintln(os.Stderr, "\nDatastore already initialized.")
+			fmt.Fprintf(os.Stderr, "Location: %s\n\n", location)
+			st.Close()
+			os.Exit(1)
+		}
+	}
+
+	if err := st.InitSchema(ctx, schemaVersion); err != nil {
 		log.Error("failed to initialize schema: %v", err)
-		// Clean up partial creation
-		st.Close()
-		os.Remove(dbPath)
-		os.Exit(1)
+		abortCreate(st, existed)
+	}
+
+	rs := newRBACStore(st.DB())
+	if err := rs.InitSchema(); err != nil {
+		log.Error("failed to initialize rbac schema: %v", err)
+		abortCreate(st, existed)
+	}
+	operator := operatorName()
+	if err := seedAdminRole(rs, operator); err != nil {
+		log.Error("failed to create the admin role: %v", err)
+		abortCreate(st, existed)
+	}
+	if operator != "" {
+		token, err := issueAdminToken(operator)
+		if err == nil {
+			err = writePrivateFile(adminTokenPath(), []byte(token+"\n"))
+		}
+		if err != nil {
+			log.Error("failed to issue the operator's admin token: %v", err)
+			abortCreate(st, existed)
+		}
+	}
+	auditLog := newAuditLog(st.DB())
+	if err := auditLog.InitSchema(); err != nil {
+		log.Error("failed to initialize audit schema: %v", err)
+		abortCreate(st, existed)
+	}
+	if err := recordCLIAudit(auditLog, audit.ActionDBCreate, location, map[string]any{"schema": schemaVersion}); err != nil {
+		log.Error("failed to record audit entry: %v", err)
 	}
 
-	log.Info("datastore created successfully path=%s schema=%s", dbPath, schemaVersion)
+	logger.With(log, "driver", storeDriver, "location", location, "schema", schemaVersion).Info("datastore created successfully")
 	fmt.Fprintf(os.Stdout, "\n✓ Datastore created successfully\n")
-	fmt.Fprintf(os.Stdout, "  Path: %s\n", dbPath)
-	fmt.Fprintf(os.Stdout, "  Schema version: %s\n\n", schemaVersion)
+	fmt.Fprintf(os.Stdout, "  Path: %s\n", location)
+	fmt.Fprintf(os.Stdout, "  Schema version: %s\n", schemaVersion)
+	if operator != "" {
+		fmt.Fprintf(os.Stdout, "  Role %q granted to %s\n", adminRole, operator)
+		fmt.Fprintf(os.Stdout, "  Admin token for %s: %s\n", operator, adminTokenPath())
+	}
+	fmt.Fprintln(os.Stdout)
+}
+
+// abortCreate closes st after a failed db create and exits. A SQLite file
+// that create made itself is removed; one that existed before, and
+// PostgreSQL tables, are left for the administrator to inspect.
+func abortCreate(st appStore, existed bool) {
+	st.Close()
+	if storeDriver == driverSQLite && !existed {
+		os.Remove(store.GetDBPath(store.GetStorePath()))
+	}
+	os.Exit(1)
 }
 
-func runDBUpgrade(cmd *cobra.Command, args []string) {
-	log.Info("db upgrade: TODO - implement migrations")
+// openReadyStore opens the datastore for CLI commands that need an
+// initialized store with the expected schema version. It exits the process
+// with guidance when the store is missing or needs attention.
+func openReadyStore(ctx context.Context) appStore {
+	requireStoreExists()
+
+	st, err := newAppStore()
+	if err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+	if err := st.Open(ctx); err != nil {
+		log.Error("failed to open datastore: %v", err)
+		os.Exit(1)
+	}
+
+	state, err := st.CheckState(ctx)
+	if err != nil {
+		log.Error("failed to check datastore state: %v", err)
+		st.Close()
+		os.Exit(1)
+	}
+	if state != store.StateReady {
+		actualVersion, _ := st.GetSchemaVersion(ctx)
+		logger.With(log, "expected", schemaVersion, "actual", actualVersion, "state", state).Error("datastore not ready")
+		switch state {
+		case store.StateUninitialized:
+			fmt.Fprintf(os.Stderr, "\nRun: %s db create\n\n", filepath.Base(os.Args[0]))
+		case store.StateNeedsUpgrade:
+			fmt.Fprintf(os.Stderr, "\nRun: %s db upgrade\n\n", filepath.Base(os.Args[0]))
+		case store.StateTooNew:
+			fmt.Fprintln(os.Stderr, "\nThe datastore was upgraded by a newer release; install it to continue.")
+		}
+		st.Close()
+		os.Exit(1)
+	}
+
+	return st
 }
 
 func runDBVerify(cmd *cobra.Command, args []string) {
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/audit"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// schemaMigrations upgrade a datastore created by an older release. To
+// change the schema, append a migration and set schemaVersion to its
+// version. "0.1" is created by db create and needs no migration.
+var schemaMigrations = []store.Migration{}
+
+func runDBUpgrade(cmd *cobra.Command, args []string) {
+	requireStoreExists()
+	ctx := cmd.Context()
+	location := storeLocation()
+
+	st, err := newAppStore()
+	if err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+	if err := st.Open(ctx); err != nil {
+		log.Error("failed to open datastore: %v", err)
+		os.Exit(1)
+	}
+	defer st.Close()
+
+	state, err := st.CheckState(ctx)
+	if err != nil {
+		log.Error("failed to check datastore state: %v", err)
+		st.Close()
+		os.Exit(1)
+	}
+	from, _ := st.GetSchemaVersion(ctx)
+	l := logger.With(log, "location", location, "from", from, "to", schemaVersion)
+	switch state {
+	case store.StateReady:
+		fmt.Fprintf(os.Stdout, "Datastore is already at schema %s.\n", schemaVersion)
+		return
+	case store.StateNeedsUpgrade:
+	case store.StateUninitialized:
+		l.Error("datastore uninitialized; run db create")
+		st.Close()
+		os.Exit(1)
+	default:
+		l.Error("datastore cannot be upgraded (state %s)", state)
+		st.Close()
+		os.Exit(1)
+	}
+
+	applied, err := store.Migrate(ctx, st, schemaMigrations, schemaVersion)
+	if len(applied) > 0 {
+		al := newAuditLog(st.DB())
+		if aerr := al.InitSchema(); aerr != nil {
+			log.Error("failed to initialize audit schema: %v", aerr)
+		} else if aerr := recordCLIAudit(al, audit.ActionDBUpgrade, location, map[string]any{"from": from, "applied": applied}); aerr != nil {
+			log.Error("failed to record audit entry: %v", aerr)
+		}
+	}
+	if err != nil {
+		l.Error("db upgrade failed: %v", err)
+		if len(applied) > 0 {
+			fmt.Fprintf(os.Stderr, "\nApplied before the failure: %s\n\n", strings.Join(applied, ", "))
+		}
+		st.Close()
+		os.Exit(1)
+	}
+
+	l.Info("datastore upgraded")
+	fmt.Fprintf(os.Stdout, "\n✓ Datastore upgraded from schema %s to %s\n", from, schemaVersion)
+	fmt.Fprintf(os.Stdout, "  Applied: %s\n\n", strings.Join(applied, ", "))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/assets"
+	"github.com/maloquacious/goobtool/internal/csrf"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/render"
+	"github.com/maloquacious/goobtool/internal/static"
+	"github.com/maloquacious/goobtool/public"
+	"github.com/maloquacious/goobtool/templates"
+)
+
+var (
+	devMode      bool
+	templatesDir string
+)
+
+// pageFuncs returns the functions available to every page template.
+// csrfHeaders reads the token the public listener's CSRF middleware puts on
+// every request, so HTMX requests from a page pass the CSRF check.
+func pageFuncs(files *static.Handler) template.FuncMap {
+	return template.FuncMap{
+		"asset":       files.URL,
+		"vendored":    vendored,
+		"vendorURL":   func(name string) (string, error) { return vendorURL(files, name) },
+		"vendorSRI":   vendorSRI,
+		"cspNonce":    cspNonce,
+		"csrfHeaders": csrf.HXHeadersJSON,
+	}
+}
+
+// vendorURL returns the fingerprinted URL of the embedded copy of a pinned
+// frontend library.
+func vendorURL(files *static.Handler, name string) (string, error) {
+	a, ok := public.Vendor(name)
+	if !ok {
+		return "", fmt.Errorf("unknown vendored library %q", name)
+	}
+	if !a.Vendored() {
+		return "", fmt.Errorf("library %q is not vendored; run go generate ./public", name)
+	}
+	return files.URL(a.Path)
+}
+
+// vendored reports whether a pinned library has an embedded copy. Pages
+// leave out libraries that have none rather than load them upstream.
+func vendored(name string) bool {
+	a, ok := public.Vendor(name)
+	return ok && a.Vendored()
+}
+
+// vendorSRI returns the integrity attribute value for a pinned library.
+func vendorSRI(name string) (string, error) {
+	a, ok := public.Vendor(name)
+	if !ok {
+		return "", fmt.Errorf("unknown vendored library %q", name)
+	}
+	return a.Integrity, nil
+}
+
+// newStaticHandler returns the /public/ handler over the public assets,
+// indexed once, or on every request with --dev.
+func newStaticHandler() *static.Handler {
+	if err := public.CheckVendor(); err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+	if missing := public.Unvendored(); len(missing) > 0 {
+		logger.With(log, "libraries", strings.Join(missing, ", ")).Warn("frontend libraries not vendored, pages render without them; run go generate ./public")
+	}
+	logOverlay("public assets", publicDir)
+	files, err := static.New(publicFS(), static.Options{Prefix: "/public/", Reload: devMode})
+	if err != nil {
+		log.Error("failed to load public assets: %v", err)
+		os.Exit(1)
+	}
+	return files
+}
+
+// newPageRenderer returns the page renderer: the --templates directory
+// overlaid on the embedded templates, parsed once at startup, or with --dev
+// reparsed on every request.
+func newPageRenderer(files *static.Handler) *render.Renderer {
+	logOverlay("templates", templatesDir)
+	opts := render.Options{FS: assets.Overlay(templatesDir, templates.FS), Funcs: pageFuncs(files)}
+	if devMode {
+		opts.Reload = true
+		logger.With(log, "dir", templatesDir).Warn("development mode: templates and public assets reload from disk on every request")
+	}
+	pages, err := render.New(opts)
+	if err != nil {
+		log.Error("failed to load templates: %v", err)
+		os.Exit(1)
+	}
+	return pages
+}
+
+// pageHandler renders page name with data, or only its content fragment
+// for HTMX requests.
+func pageHandler(pages *render.Renderer, name string, data any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if err := pages.Render(w, r, name, data); err != nil {
+			logger.FromContext(r.Context(), log).Error("failed to render page %s: %v", name, err)
+			http.Error(w, "page unavailable", http.StatusInternalServerError)
+		}
+	})
+}
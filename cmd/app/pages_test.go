@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/csrf"
+	"github.com/maloquacious/goobtool/internal/render"
+	"github.com/maloquacious/goobtool/internal/static"
+	"github.com/maloquacious/goobtool/public"
+	"github.com/maloquacious/goobtool/templates"
+)
+
+// TestPageCSRFHeaders checks that pages carry the CSRF token in hx-headers
+// and that an unsafe request sending those headers passes the check.
+func TestPageCSRFHeaders(t *testing.T) {
+	files, err := static.New(public.FS, static.Options{Prefix: "/public/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := render.New(render.Options{FS: templates.FS, Funcs: pageFuncs(files)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	protect, err := csrf.New(csrf.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /", pageHandler(pages, "index", nil))
+	mux.HandleFunc("POST /action", func(w http.ResponseWriter, r *http.Request) {})
+	h := protect.Protect(mux)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / = %d: %s", rec.Code, rec.Body)
+	}
+	m := regexp.MustCompile(`<body hx-headers='([^']*)'>`).FindStringSubmatch(rec.Body.String())
+	if m == nil {
+		t.Fatalf("no hx-headers on <body>:\n%s", rec.Body)
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(html.UnescapeString(m[1])), &headers); err != nil {
+		t.Fatalf("hx-headers %q: %v", m[1], err)
+	}
+	if headers[csrf.DefaultHeaderName] == "" {
+		t.Fatalf("hx-headers %v lacks %s", headers, csrf.DefaultHeaderName)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/action", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("POST with hx-headers = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/audit"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/rbac"
+	"github.com/spf13/cobra"
+)
+
+var (
+	roleDescription string
+	tokenOut        string
+)
+
+// newRBACCmd builds the `rbac` command group for managing roles,
+// permissions and user assignments directly in the datastore.
+func newRBACCmd() *cobra.Command {
+	rbacCmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Role-based access control management",
+	}
+
+	roleCmd := &cobra.Command{
+		Use:   "role",
+		Short: "Manage roles",
+	}
+	roleCreateCmd := &cobra.Command{
+		Use:   "create <role>",
+		Short: "Create a role",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRBACRoleCreate,
+	}
+	roleCreateCmd.Flags().StringVar(&roleDescription, "description", "", "human readable role description")
+	roleDeleteCmd := &cobra.Command{
+		Use:   "delete <role>",
+		Short: "Delete a role and its grants and assignments",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRBACRoleDelete,
+	}
+	roleListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List roles and their permissions",
+		Args:  cobra.NoArgs,
+		Run:   runRBACRoleList,
+	}
+	roleCmd.AddCommand(roleCreateCmd, roleDeleteCmd, roleListCmd)
+
+	grantCmd := &cobra.Command{
+		Use:   "grant <role> <permission>",
+		Short: "Grant a permission to a role",
+		Args:  cobra.ExactArgs(2),
+		Run:   runRBACGrant,
+	}
+	revokeCmd := &cobra.Command{
+		Use:   "revoke <role> <permission>",
+		Short: "Revoke a permission from a role",
+		Args:  cobra.ExactArgs(2),
+		Run:   runRBACRevoke,
+	}
+	assignCmd := &cobra.Command{
+		Use:   "assign <user> <role>",
+		Short: "Assign a role to a user",
+		Args:  cobra.ExactArgs(2),
+		Run:   runRBACAssign,
+	}
+	unassignCmd := &cobra.Command{
+		Use:   "unassign <user> <role>",
+		Short: "Remove a role from a user",
+		Args:  cobra.ExactArgs(2),
+		Run:   runRBACUnassign,
+	}
+	showCmd := &cobra.Command{
+		Use:   "show <user>",
+		Short: "Show the roles and effective permissions of a user",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRBACShow,
+	}
+
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the admin tokens that identify operators to the admin API",
+	}
+	tokenIssueCmd := &cobra.Command{
+		Use:   "issue <user>",
+		Short: "Issue a new admin token for a user, replacing any earlier one",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRBACTokenIssue,
+	}
+	tokenIssueCmd.Flags().StringVar(&tokenOut, "out", "", "write the token to this file (mode 0600) instead of printing it")
+	tokenRevokeCmd := &cobra.Command{
+		Use:   "revoke <user>",
+		Short: "Revoke a user's admin token",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRBACTokenRevoke,
+	}
+	tokenCmd.AddCommand(tokenIssueCmd, tokenRevokeCmd)
+
+	rbacCmd.AddCommand(roleCmd, grantCmd, revokeCmd, assignCmd, unassignCmd, showCmd, tokenCmd)
+	return rbacCmd
+}
+
+// withRBAC opens the ready datastore, ensures the RBAC tables exist and
+// runs fn. Any error is logged and the process exits non-zero.
+func withRBAC(action string, fn func(rbac.Store) error) {
+	runRBAC(action, nil, fn)
+}
+
+// mutateRBAC is withRBAC for commands that change grants; on success it
+// records the command and its arguments in the audit log.
+func mutateRBAC(action string, args []string, fn func(rbac.Store) error) {
+	runRBAC(action, args, fn)
+}
+
+func runRBAC(action string, auditArgs []string, fn func(rbac.Store) error) {
+	st := openReadyStore(context.Background())
+	defer st.Close()
+
+	rs := newRBACStore(st.DB())
+	if err := rs.InitSchema(); err != nil {
+		log.Error("failed to initialize rbac schema: %v", err)
+		st.Close()
+		os.Exit(1)
+	}
+
+	if err := fn(rs); err != nil {
+		log.Error("rbac %s failed: %v", action, err)
+		if errors.Is(err, rbac.ErrRoleNotFound) || errors.Is(err, rbac.ErrRoleExists) {
+			fmt.Fprintf(os.Stderr, "\n%v\n\n", err)
+		}
+		st.Close()
+		os.Exit(1)
+	}
+
+	if auditArgs != nil {
+		al := newAuditLog(st.DB())
+		if err := al.InitSchema(); err != nil {
+			log.Error("failed to initialize audit schema: %v", err)
+			st.Close()
+			os.Exit(1)
+		}
+		details := map[string]any{"command": action, "args": auditArgs}
+		if err := recordCLIAudit(al, audit.ActionRBACChange, strings.Join(auditArgs, " "), details); err != nil {
+			log.Error("failed to record audit entry: %v", err)
+			st.Close()
+			os.Exit(1)
+		}
+	}
+}
+
+func runRBACRoleCreate(cmd *cobra.Command, args []string) {
+	mutateRBAC("role create", args, func(rs rbac.Store) error {
+		if err := rs.CreateRole(args[0], roleDescription); err != nil {
+			return err
+		}
+		logger.With(log, "role", args[0]).Info("rbac role created")
+		fmt.Fprintf(os.Stdout, "✓ Role %q created\n", args[0])
+		return nil
+	})
+}
+
+func runRBACRoleDelete(cmd *cobra.Command, args []string) {
+	mutateRBAC("role delete", args, func(rs rbac.Store) error {
+		if err := rs.DeleteRole(args[0]); err != nil {
+			return err
+		}
+		logger.With(log, "role", args[0]).Info("rbac role deleted")
+		fmt.Fprintf(os.Stdout, "✓ Role %q deleted\n", args[0])
+		return nil
+	})
+}
+
+func runRBACRoleList(cmd *cobra.Command, args []string) {
+	withRBAC("role list", func(rs rbac.Store) error {
+		roles, err := rs.ListRoles()
+		if err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			fmt.Fprintln(os.Stdout, "No roles defined.")
+			return nil
+		}
+		for _, role := range roles {
+			fmt.Fprintf(os.Stdout, "%s", role.Name)
+			if role.Description != "" {
+				fmt.Fprintf(os.Stdout, " — %s", role.Description)
+			}
+			fmt.Fprintln(os.Stdout)
+			if len(role.Permissions) > 0 {
+				fmt.Fprintf(os.Stdout, "  permissions: %s\n", strings.Join(role.Permissions, ", "))
+			}
+		}
+		return nil
+	})
+}
+
+func runRBACGrant(cmd *cobra.Command, args []string) {
+	mutateRBAC("grant", args, func(rs rbac.Store) error {
+		if err := rs.Grant(args[0], args[1]); err != nil {
+			return err
+		}
+		logger.With(log, "role", args[0], "permission", args[1]).Info("rbac permission granted")
+		fmt.Fprintf(os.Stdout, "✓ Granted %q to role %q\n", args[1], args[0])
+		return nil
+	})
+}
+
+func runRBACRevoke(cmd *cobra.Command, args []string) {
+	mutateRBAC("revoke", args, func(rs rbac.Store) error {
+		if err := rs.Revoke(args[0], args[1]); err != nil {
+			return err
+		}
+		logger.With(log, "role", args[0], "permission", args[1]).Info("rbac permission revoked")
+		fmt.Fprintf(os.Stdout, "✓ Revoked %q from role %q\n", args[1], args[0])
+		return nil
+	})
+}
+
+func runRBACAssign(cmd *cobra.Command, args []string) {
+	mutateRBAC("assign", args, func(rs rbac.Store) error {
+		if err := rs.Assign(args[0], args[1]); err != nil {
+			return err
+		}
+		logger.With(log, "user", args[0], "role", args[1]).Info("rbac role assigned")
+		fmt.Fprintf(os.Stdout, "✓ Assigned role %q to user %q\n", args[1], args[0])
+		return nil
+	})
+}
+
+func runRBACUnassign(cmd *cobra.Command, args []string) {
+	mutateRBAC("unassign", args, func(rs rbac.Store) error {
+		if err := rs.Unassign(args[0], args[1]); err != nil {
+			return err
+		}
+		logger.With(log, "user", args[0], "role", args[1]).Info("rbac role unassigned")
+		fmt.Fprintf(os.Stdout, "✓ Removed role %q from user %q\n", args[1], args[0])
+		return nil
+	})
+}
+
+func runRBACShow(cmd *cobra.Command, args []string) {
+	withRBAC("show", func(rs rbac.Store) error {
+		roles, err := rs.RolesFor(args[0])
+		if err != nil {
+			return err
+		}
+		perms, err := rs.PermissionsFor(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "User: %s\n", args[0])
+		fmt.Fprintf(os.Stdout, "  roles: %s\n", strings.Join(roles, ", "))
+		fmt.Fprintf(os.Stdout, "  permissions: %s\n", strings.Join(perms, ", "))
+		return nil
+	})
+}
+
+func runRBACTokenIssue(cmd *cobra.Command, args []string) {
+	mutateRBAC("token issue", args, func(rs rbac.Store) error {
+		token, err := issueAdminToken(args[0])
+		if err != nil {
+			return err
+		}
+		logger.With(log, "user", args[0]).Info("admin token issued")
+		if tokenOut != "" {
+			if err := writePrivateFile(tokenOut, []byte(token+"\n")); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "✓ Admin token for %s written to %s\n", args[0], tokenOut)
+			return nil
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", token)
+		fmt.Fprintf(os.Stderr, "Admin token for %s; it is not stored and cannot be shown again.\n", args[0])
+		return nil
+	})
+}
+
+func runRBACTokenRevoke(cmd *cobra.Command, args []string) {
+	mutateRBAC("token revoke", args, func(rs rbac.Store) error {
+		ok, err := revokeAdminToken(args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s has no admin token", args[0])
+		}
+		logger.With(log, "user", args[0]).Info("admin token revoked")
+		fmt.Fprintf(os.Stdout, "✓ Admin token for %s revoked\n", args[0])
+		return nil
+	})
+}
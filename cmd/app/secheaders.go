@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/secheaders"
+)
+
+// cspReportPath receives CSP violation reports on the public listener.
+const cspReportPath = "/csp-report"
+
+var (
+	cspPolicy      string
+	cspReportOnly  bool
+	hstsMaxAge     time.Duration
+	frameAncestors string
+)
+
+// newSecurityHeaders returns the security headers middleware for the public
+// listener, configured from --csp, --csp-report-only, --hsts-max-age and
+// --frame-ancestors.
+func newSecurityHeaders() func(http.Handler) http.Handler {
+	policy := cspPolicy
+	if policy == "" {
+		log.Warn("content security policy disabled (--csp is empty)")
+	}
+	if policy != "" && cspReportOnly {
+		logger.With(log, "endpoint", cspReportPath).Warn("content security policy is report-only; violations are logged, not blocked")
+	}
+	return secheaders.Middleware(secheaders.Options{
+		Policy:            policy,
+		ReportOnly:        cspReportOnly,
+		ReportURI:         cspReportPath,
+		FrameAncestors:    frameAncestors,
+		HSTSMaxAge:        hstsMaxAge,
+		ReferrerPolicy:    secheaders.DefaultReferrerPolicy,
+		PermissionsPolicy: secheaders.DefaultPermissionsPolicy,
+	})
+}
+
+// cspNonce is the page template function for the nonce attribute of inline
+// and vendored scripts and styles.
+func cspNonce(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return secheaders.Nonce(r.Context())
+}
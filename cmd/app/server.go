@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminTokenFlag string
+	statusWatch    bool
+	statusInterval time.Duration
+	statusJSON     bool
+)
+
+// newServerCmd builds the `server` command group, which talks to a running
+// server over the admin listener.
+func newServerCmd() *cobra.Command {
+	serverCmd := &cobra.Command{
+		Use:   "server",
+		Short: "Commands for a running server (via the admin API)",
+	}
+	serverCmd.PersistentFlags().IntVar(&adminPort, "admin-port", 8383, "admin HTTP port of the running server")
+	serverCmd.PersistentFlags().StringVar(&adminHost, "admin-host", "127.0.0.1", "admin host of the running server")
+	serverCmd.PersistentFlags().StringVar(&adminTokenFlag, "token-file", "", "file holding your admin token (default: admin.token in the store directory)")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show server status from /admin/status",
+		Args:  cobra.NoArgs,
+		Run:   runServerStatus,
+	}
+	statusCmd.Flags().BoolVar(&statusWatch, "watch", false, "redraw the status until interrupted")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "refresh interval for --watch")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "print the raw JSON response")
+
+	shutdownCmd := &cobra.Command{
+		Use:   "shutdown",
+		Short: "Shut the server down gracefully via /admin/shutdown",
+		Args:  cobra.NoArgs,
+		Run:   func(cmd *cobra.Command, args []string) { runServerAction("/admin/shutdown") },
+	}
+	restartCmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the server via /admin/restart (needs a supervisor to start it again)",
+		Args:  cobra.NoArgs,
+		Run:   func(cmd *cobra.Command, args []string) { runServerAction("/admin/restart") },
+	}
+
+	serverCmd.AddCommand(statusCmd, shutdownCmd, restartCmd)
+	return serverCmd
+}
+
+// runServerAction posts to a state-changing admin route and prints the
+// status the server reports.
+func runServerAction(path string) {
+	raw, err := adminPost(context.Background(), path)
+	if err != nil {
+		logger.With(log, "path", path).Error("admin request failed: %v", err)
+		os.Exit(1)
+	}
+	var resp struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil || resp.Status == "" {
+		os.Stdout.Write(raw)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "Server %s.\n", resp.Status)
+}
+
+// adminPost sends an empty JSON POST to path on the admin listener,
+// authenticated with the operator's admin token. It first fetches a CSRF
+// token from /admin/csrf, keeping the cookie that comes with it, since the
+// admin listener rejects unsafe requests without one.
+func adminPost(ctx context.Context, path string) ([]byte, error) {
+	tokenPath := adminTokenFlag
+	if tokenPath == "" {
+		tokenPath = adminTokenPath()
+	}
+	adminToken, err := readTokenFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	base := "http://" + net.JoinHostPort(adminHost, strconv.Itoa(adminPort))
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	client := &http.Client{Jar: jar, Timeout: 5 * time.Second}
+
+	raw, err := adminDo(ctx, client, http.MethodGet, base+"/admin/csrf", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get csrf token: %w", err)
+	}
+	var token struct {
+		Token  string `json:"token"`
+		Header string `json:"header"`
+	}
+	if err := json.Unmarshal(raw, &token); err != nil || token.Token == "" || token.Header == "" {
+		return nil, fmt.Errorf("unexpected csrf response %q", raw)
+	}
+
+	return adminDo(ctx, client, http.MethodPost, base+path, http.Header{
+		token.Header:    {token.Token},
+		"Authorization": {"Bearer " + adminToken},
+	})
+}
+
+// adminDo sends a JSON request to the admin API and returns the body of a
+// 200 response.
+func adminDo(ctx context.Context, client *http.Client, method, url string, header http.Header) ([]byte, error) {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("{}")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach admin API: %w", err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return raw, fmt.Errorf("admin API returned %s: %s", res.Status, strings.TrimSpace(string(raw)))
+	}
+	return raw, nil
+}
+
+func runServerStatus(cmd *cobra.Command, args []string) {
+	if !statusWatch {
+		resp, raw, err := fetchStatus(context.Background())
+		if err != nil {
+			log.Error("failed to get server status: %v", err)
+			os.Exit(1)
+		}
+		printStatus(os.Stdout, resp, raw)
+		return
+	}
+
+	if statusInterval <= 0 {
+		log.Error("--interval must be positive")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		// clear the screen and move the cursor home before each redraw
+		fmt.Fprint(os.Stdout, "\033[H\033[2J")
+		resp, raw, err := fetchStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "error: %v\n", err)
+		} else {
+			printStatus(os.Stdout, resp, raw)
+		}
+		fmt.Fprintf(os.Stdout, "\nrefreshing every %s, Ctrl-C to quit\n", statusInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchStatus requests /admin/status from the configured admin listener.
+func fetchStatus(ctx context.Context) (statusResponse, []byte, error) {
+	var resp statusResponse
+	url := "http://" + net.JoinHostPort(adminHost, strconv.Itoa(adminPort)) + "/admin/status"
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to reach admin API: %w", err)
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return resp, raw, fmt.Errorf("admin API returned %s", res.Status)
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return resp, raw, fmt.Errorf("failed to decode status: %w", err)
+	}
+	return resp, raw, nil
+}
+
+// printStatus writes the status as text, or as the raw JSON with --json.
+func printStatus(w io.Writer, s statusResponse, raw []byte) {
+	if statusJSON {
+		w.Write(raw)
+		return
+	}
+	fmt.Fprintf(w, "Mode:        %s\n", s.Mode)
+	fmt.Fprintf(w, "Version:     %s (built %s)\n", s.Version, valueOr(s.BuildDate, "unknown"))
+	fmt.Fprintf(w, "Go:          %s\n", valueOr(s.GoVersion, "unknown"))
+	fmt.Fprintf(w, "Schema:      %s (db %s)\n", s.SchemaVersion, valueOr(s.DBVersion, "none"))
+	fmt.Fprintf(w, "Uptime:      %s (since %s)\n", s.Uptime, s.StartTime)
+	fmt.Fprintf(w, "Goroutines:  %d\n", s.Goroutines)
+	for _, name := range sortedKeys(s.Listeners) {
+		fmt.Fprintf(w, "Listener:    %-8s %s\n", name, s.Listeners[name])
+	}
+	if s.DB != nil {
+		fmt.Fprintf(w, "DB size:     %d bytes (%d pages of %d), WAL %d bytes\n", s.DB.SizeBytes, s.DB.PageCount, s.DB.PageSize, s.DB.WALSizeBytes)
+		fmt.Fprintf(w, "DB conns:    %d open\n", s.DB.OpenConnections)
+		fmt.Fprintf(w, "Migrated:    %s\n", valueOr(s.DB.LastMigration, "unknown"))
+	}
+	fmt.Fprintln(w, "Health:")
+	for _, name := range sortedKeys(s.Health) {
+		fmt.Fprintf(w, "  %-16s %s\n", name, s.Health[name])
+	}
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
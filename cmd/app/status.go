@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/health"
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// statusResponse is the /admin/status payload. The server status command
+// decodes the same type.
+type statusResponse struct {
+	Version       string            `json:"version"`
+	SchemaVersion string            `json:"schemaVersion"`
+	DBVersion     string            `json:"dbVersion"`
+	BuildDate     string            `json:"buildDate"`
+	CommitDate    string            `json:"commitDate"`
+	GoVersion     string            `json:"goVersion"`
+	Revision      string            `json:"revision,omitempty"`
+	Dirty         bool              `json:"dirty"`
+	Time          string            `json:"time"`
+	Mode          string            `json:"mode"`
+	StartTime     string            `json:"startTime"`
+	Uptime        string            `json:"uptime"`
+	UptimeSeconds float64           `json:"uptimeSeconds"`
+	Goroutines    int               `json:"goroutines"`
+	Listeners     map[string]string `json:"listeners"`
+	DB            *dbStatus         `json:"db,omitempty"`
+	Health        map[string]string `json:"health"`
+}
+
+// dbStatus is the datastore section of statusResponse.
+type dbStatus struct {
+	SizeBytes       int64  `json:"sizeBytes"`
+	WALSizeBytes    int64  `json:"walSizeBytes"`
+	PageCount       int64  `json:"pageCount"`
+	PageSize        int64  `json:"pageSize"`
+	OpenConnections int    `json:"openConnections"`
+	LastMigration   string `json:"lastMigration,omitempty"`
+}
+
+// serverInfo carries what the status handler reports about a running server.
+// listeners is filled in once the sockets are bound, before serving starts.
+type serverInfo struct {
+	mode      string
+	st        appStore
+	health    *health.Registry
+	listeners map[string]string
+}
+
+// statusHandler serves /admin/status for info.
+func statusHandler(info *serverInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(buildStatus(r.Context(), info))
+	})
+}
+
+// buildStatus collects the status payload. Failures are reported in the
+// health map rather than failing the request.
+func buildStatus(ctx context.Context, info *serverInfo) statusResponse {
+	now := time.Now()
+	uptime := now.Sub(startTime)
+	build := buildInfo()
+	resp := statusResponse{
+		Version:       build.Version,
+		SchemaVersion: schemaVersion,
+		BuildDate:     build.BuildTime,
+		CommitDate:    build.CommitTime,
+		GoVersion:     build.GoVersion,
+		Revision:      build.Revision,
+		Dirty:         build.Dirty,
+		Time:          now.UTC().Format(time.RFC3339),
+		Mode:          info.mode,
+		StartTime:     startTime.UTC().Format(time.RFC3339),
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		Listeners:     map[string]string{},
+		Health:        map[string]string{},
+	}
+
+	for name, addr := range info.listeners {
+		resp.Listeners[name] = addr
+		resp.Health["listener."+name] = "ok"
+	}
+
+	if info.health != nil {
+		for _, res := range info.health.Check(ctx).Results {
+			resp.Health[res.Name] = res.Status
+			if res.Error != "" {
+				resp.Health[res.Name] += ": " + res.Error
+			}
+		}
+	}
+
+	if info.st == nil || info.st.DB() == nil {
+		return resp
+	}
+	stats, err := info.st.Stats(ctx)
+	if err != nil {
+		resp.Health["store.stats"] = health.StatusFail + ": " + err.Error()
+		return resp
+	}
+	resp.DBVersion = stats.SchemaVersion
+	resp.DB = &dbStatus{
+		SizeBytes:       stats.SizeBytes,
+		WALSizeBytes:    stats.WALSizeBytes,
+		PageCount:       stats.PageCount,
+		PageSize:        stats.PageSize,
+		OpenConnections: stats.OpenConnections,
+	}
+	if !stats.LastMigration.IsZero() {
+		resp.DB.LastMigration = stats.LastMigration.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// newHealthRegistry registers the readiness probes for st. Disk and WAL
+// probes apply to the SQLite file only.
+func newHealthRegistry(st appStore) *health.Registry {
+	reg := health.NewRegistry(readyTimeout)
+	reg.Register("store", storeReadProbe(st))
+	if storeDriver == driverSQLite {
+		reg.Register("disk", health.DiskSpaceProbe(store.GetStorePath(), uint64(readyMinDiskMB)<<20))
+		reg.Register("wal", health.WALSizeProbe(store.GetDBPath(store.GetStorePath()), readyMaxWALMB<<20))
+	}
+	return reg
+}
+
+// storeReadProbe checks that st answers a read. It goes through
+// WithReadTx rather than pinging st.DB(), the SQLite writer pool of one
+// connection, which stays busy under write load while reads still work.
+func storeReadProbe(st appStore) health.Probe {
+	return func(ctx context.Context) error {
+		return st.WithReadTx(ctx, func(tx store.Tx) error {
+			var one int
+			return tx.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/health"
+	"github.com/maloquacious/goobtool/internal/store/sqlite"
+)
+
+// TestStoreReadProbeWhileWriting checks that readiness does not wait for
+// the SQLite writer, which a long write transaction holds.
+func TestStoreReadProbeWhileWriting(t *testing.T) {
+	st := sqlite.New(filepath.Join(t.TempDir(), "test.db"), schemaVersion)
+	if err := st.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	tx, err := st.DB().BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := health.PingProbe(st.DB())(ctx); err == nil {
+		t.Fatal("writer ping succeeded while its only connection was in use")
+	}
+	if err := storeReadProbe(st)(context.Background()); err != nil {
+		t.Errorf("read probe: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/certs"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tlsCert          string
+	tlsKey           string
+	tlsWatchInterval time.Duration
+	httpRedirectPort int
+
+	certHosts    []string
+	certValidFor time.Duration
+	certOut      string
+	keyOut       string
+	certForce    bool
+)
+
+// setupTLS loads --tls-cert and --tls-key and reloads them on SIGHUP and,
+// unless --tls-watch-interval is 0, when the files change, until ctx is
+// done. It returns nil when TLS is off.
+func setupTLS(ctx context.Context) *certs.Reloader {
+	if tlsCert == "" && tlsKey == "" {
+		if httpRedirectPort != 0 {
+			log.Error("--http-redirect-port requires --tls-cert and --tls-key")
+			os.Exit(1)
+		}
+		return nil
+	}
+	if tlsCert == "" || tlsKey == "" {
+		log.Error("--tls-cert and --tls-key must be set together")
+		os.Exit(1)
+	}
+	r, err := certs.NewReloader(tlsCert, tlsKey, log)
+	if err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Info("SIGHUP received, reloading tls certificate")
+				if err := r.Reload(); err != nil {
+					log.Error("tls certificate reload failed, keeping the current one: %v", err)
+				}
+			}
+		}
+	}()
+	if tlsWatchInterval > 0 {
+		go r.Watch(ctx, tlsWatchInterval)
+	}
+	return r
+}
+
+// servePublic serves srv on l, over TLS when tlsCerts is set.
+func servePublic(srv *http.Server, l net.Listener, tlsCerts *certs.Reloader) error {
+	if tlsCerts == nil {
+		return srv.Serve(l)
+	}
+	srv.TLSConfig = certs.Config(tlsCerts)
+	return srv.ServeTLS(l, "", "")
+}
+
+// listenRedirect binds --http-redirect-port and returns a server that sends
+// every request there to the HTTPS listener on port. It returns nils when
+// the redirect port is off.
+func listenRedirect(port int) (*http.Server, net.Listener, error) {
+	if httpRedirectPort == 0 {
+		return nil, nil, nil
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(httpRedirectPort)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("redirect listener bind failed: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           redirectHandler(port),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv, l, nil
+}
+
+// redirectHandler permanently redirects to the same host and path on
+// httpsPort, keeping the method and body (308).
+func redirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			// bracketed IPv6 literal without a port
+			host = host[1 : len(host)-1]
+		}
+		if host == "" {
+			http.Error(w, "missing Host header", http.StatusBadRequest)
+			return
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		w.Header().Set("Connection", "close")
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// newCertCmd builds the `cert` command group.
+func newCertCmd() *cobra.Command {
+	certCmd := &cobra.Command{
+		Use:   "cert",
+		Short: "TLS certificate commands",
+	}
+
+	selfSignedCmd := &cobra.Command{
+		Use:   "self-signed",
+		Short: "Create a self-signed certificate for development",
+		Args:  cobra.NoArgs,
+		Run:   runCertSelfSigned,
+	}
+	selfSignedCmd.Flags().StringSliceVar(&certHosts, "host", []string{"localhost", "127.0.0.1", "::1"}, "DNS names and IP addresses the certificate is valid for")
+	selfSignedCmd.Flags().DurationVar(&certValidFor, "valid-for", 90*24*time.Hour, "how long the certificate is valid")
+	selfSignedCmd.Flags().StringVar(&certOut, "cert", "cert.pem", "certificate output file")
+	selfSignedCmd.Flags().StringVar(&keyOut, "key", "key.pem", "private key output file (written with mode 0600)")
+	selfSignedCmd.Flags().BoolVar(&certForce, "force", false, "overwrite existing files")
+
+	certCmd.AddCommand(selfSignedCmd)
+	return certCmd
+}
+
+func runCertSelfSigned(cmd *cobra.Command, args []string) {
+	if !certForce {
+		for _, name := range []string{certOut, keyOut} {
+			if _, err := os.Lstat(name); err == nil {
+				logger.With(log, "file", name).Error("file already exists (use --force to overwrite)")
+				os.Exit(1)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				log.Error("failed to check %s: %v", name, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	certPEM, keyPEM, err := certs.SelfSigned(certHosts, certValidFor)
+	if err != nil {
+		log.Error("failed to create certificate: %v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(keyOut, keyPEM, 0o600); err != nil {
+		log.Error("failed to write key: %v", err)
+		os.Exit(1)
+	}
+	// WriteFile keeps the mode of a file it overwrites
+	if err := os.Chmod(keyOut, 0o600); err != nil {
+		log.Error("failed to restrict key permissions: %v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(certOut, certPEM, 0o644); err != nil {
+		log.Error("failed to write certificate: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stdout, "Wrote %s and %s for %s (valid for %s).\n", certOut, keyOut, strings.Join(certHosts, ", "), certValidFor)
+	fmt.Fprintf(os.Stdout, "\nServe with: %s serve --tls-cert %s --tls-key %s\n", filepath.Base(os.Args[0]), certOut, keyOut)
+}
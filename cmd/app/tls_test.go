@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		host      string
+		target    string
+		want      string
+	}{
+		{"name", 8443, "example.com", "/a?b=c", "https://example.com:8443/a?b=c"},
+		{"name with port", 8443, "example.com:8080", "/", "https://example.com:8443/"},
+		{"default port", 443, "example.com:8080", "/", "https://example.com/"},
+		{"ipv6", 8443, "[::1]", "/", "https://[::1]:8443/"},
+		{"ipv6 with port", 8443, "[::1]:8080", "/", "https://[::1]:8443/"},
+		{"ipv6 default port", 443, "[::1]", "/", "https://[::1]/"},
+		{"ipv6 with port default port", 443, "[::1]:8080", "/", "https://[::1]/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			redirectHandler(tt.httpsPort).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusPermanentRedirect {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusPermanentRedirect)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = ""
+	rec := httptest.NewRecorder()
+	redirectHandler(8443).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing Host: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
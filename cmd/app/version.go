@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/maloquacious/goobtool/internal/buildinfo"
+	"github.com/spf13/cobra"
+)
+
+// buildInfo is the one source of build metadata for the version command,
+// /version, /admin/status and the build info metric.
+var buildInfo = sync.OnceValue(func() buildinfo.Info {
+	return buildinfo.Read(version, buildDate)
+})
+
+// versionResponse is the version command's JSON output.
+type versionResponse struct {
+	buildinfo.Info
+	SchemaVersion string `json:"schemaVersion"`
+}
+
+func newVersionCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version and build information",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			info := buildInfo()
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(versionResponse{Info: info, SchemaVersion: schemaVersion}); err != nil {
+					log.Error("failed to encode version: %v", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			revision := valueOr(info.Revision, "unknown")
+			if info.Dirty {
+				revision += " (modified)"
+			}
+			fmt.Printf("Version:     %s\n", info.Version)
+			fmt.Printf("Go:          %s (%s)\n", info.GoVersion, info.Platform)
+			fmt.Printf("Revision:    %s\n", revision)
+			fmt.Printf("Committed:   %s\n", valueOr(info.CommitTime, "unknown"))
+			fmt.Printf("Built:       %s\n", valueOr(info.BuildTime, "unknown"))
+			fmt.Printf("Schema:      %s\n", schemaVersion)
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+// versionHandler serves the public /version endpoint.
+func versionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := buildInfo()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"appVersion":    info.Version,
+			"schemaVersion": schemaVersion,
+			"goVersion":     info.GoVersion,
+			"buildDate":     info.BuildTime,
+			"commitDate":    info.CommitTime,
+		})
+	})
+}
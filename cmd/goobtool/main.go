@@ -0,0 +1,211 @@
+// Command goobtool generates Goobergine applications.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/buildinfo"
+	"github.com/maloquacious/goobtool/internal/fsutil"
+	"github.com/maloquacious/goobtool/internal/generator"
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/semver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	version               = semver.Version{Minor: 1, PreRelease: "alpha"}
+	log     logger.Logger = logger.Default
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:     "goobtool",
+		Short:   "Goobergine application generator",
+		Version: buildinfo.Read(version, "").Version,
+	}
+	rootCmd.AddCommand(newNewCmd(), newUpdateCmd(), newAddCmd(), newFeaturesCmd())
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newNewCmd() *cobra.Command {
+	var (
+		cfg   generator.Config
+		force bool
+	)
+	cmd := &cobra.Command{
+		Use:   "new <dir>",
+		Short: "Generate a new application in dir",
+		Long: `Render the Goobergine skeleton into dir.
+
+The module path is required; the binary name defaults to its last element,
+the app name to the binary name, and the prefix used for cookies, env vars
+and metrics to the binary name reduced to lowercase letters and digits.
+Optional subsystems are chosen with --with; dependencies are added
+automatically. Existing files are never overwritten unless --force is given.
+
+The project records the template version and rendered file hashes in
+` + generator.LockFile + ` and keeps pristine copies under ` + generator.BaseDir + `;
+commit both so goobtool update can merge later template changes.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runNew(args[0], cfg, force)
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Module, "module", "", "Go module path (e.g. github.com/acme/widgets)")
+	cmd.Flags().StringVar(&cfg.AppName, "app-name", "", "human-readable application name")
+	cmd.Flags().StringVar(&cfg.BinaryName, "binary", "", "binary and cmd/ directory name")
+	cmd.Flags().IntVar(&cfg.Port, "port", generator.DefaultPort, "default public HTTP port")
+	cmd.Flags().IntVar(&cfg.AdminPort, "admin-port", generator.DefaultAdminPort, "default admin HTTP port")
+	cmd.Flags().StringVar(&cfg.PkgPrefix, "pkg-prefix", "", "prefix for cookie, env var and metric names")
+	cmd.Flags().StringSliceVar(&cfg.Features, "with", nil, "optional features to include (see goobtool features)")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files")
+	_ = cmd.MarkFlagRequired("module")
+	return cmd
+}
+
+func runNew(dir string, cfg generator.Config, force bool) {
+	files, err := generator.Generate(dir, cfg, force)
+	if err != nil {
+		var exists *fsutil.ExistsError
+		if errors.As(err, &exists) {
+			log.Error("refusing to overwrite %d existing file(s) in %s; use --force to overwrite", len(exists.Paths), dir)
+			for _, p := range exists.Paths {
+				fmt.Fprintf(os.Stderr, "  %s\n", p)
+			}
+			os.Exit(1)
+		}
+		log.Error("failed to generate project: %v", err)
+		os.Exit(1)
+	}
+
+	cfg.SetDefaults()
+	fmt.Printf("Created %s (%d files) in %s\n", cfg.Module, len(files), dir)
+	if features, _ := generator.ResolveFeatures(cfg.Features); len(features) > 0 {
+		fmt.Printf("Features: %s\n", strings.Join(features, ", "))
+	}
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  cd %s\n", filepath.Clean(dir))
+	fmt.Println("  go mod tidy")
+	fmt.Printf("  go run ./cmd/%s --init\n", cfg.BinaryName)
+	fmt.Printf("  go run ./cmd/%s\n", cfg.BinaryName)
+}
+
+func newUpdateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "update [dir]",
+		Short: "Merge the current skeleton into a generated application",
+		Long: `Re-render the project in dir (default ".") with this goobtool's skeleton
+and the fields recorded in its lock file.
+
+Files you have not edited are replaced. Edited files get a three-way merge
+of the template's changes; where both sides changed the same lines the file
+is written with <<<<<<< / ======= / >>>>>>> markers to resolve by hand.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			runUpdate(dir, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report what would change without writing")
+	return cmd
+}
+
+func runUpdate(dir string, dryRun bool) {
+	res, err := generator.Update(dir, dryRun)
+	if errors.Is(err, generator.ErrNoLock) {
+		log.Error("%s is not a generated project (no %s); create one with goobtool new", dir, generator.LockFile)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Error("failed to update project: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Template %s -> %s\n", res.From, res.To)
+	printChanges(res, dryRun)
+}
+
+// printChanges lists what an update or add did, and exits non-zero when
+// files were left with conflict markers.
+func printChanges(res *generator.UpdateResult, dryRun bool) {
+	for _, c := range res.Changes {
+		if c.Action == generator.ActionUnchanged {
+			continue
+		}
+		if c.Conflicts > 0 {
+			fmt.Printf("  %-10s %s (%d)\n", c.Action, c.Path, c.Conflicts)
+			continue
+		}
+		fmt.Printf("  %-10s %s\n", c.Action, c.Path)
+	}
+	if dryRun {
+		fmt.Println("Dry run: nothing written.")
+	}
+	if n := res.Conflicts(); n > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d file(s) have conflicts; resolve the <<<<<<< markers and rebuild.\n", n)
+		os.Exit(1)
+	}
+}
+
+func newAddCmd() *cobra.Command {
+	var (
+		dir    string
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add <feature>...",
+		Short: "Add optional features to a generated application",
+		Long: `Render the files of the named features, and their dependencies, into a
+generated project and re-render the files that wire them in. Edited files
+are merged as goobtool update does. The project must be at this goobtool's
+template version; run goobtool update first if it is not.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runAdd(dir, args, dryRun)
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", ".", "project directory")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report what would change without writing")
+	return cmd
+}
+
+func runAdd(dir string, features []string, dryRun bool) {
+	res, err := generator.Add(dir, features, dryRun)
+	if errors.Is(err, generator.ErrNoLock) {
+		log.Error("%s is not a generated project (no %s); create one with goobtool new", dir, generator.LockFile)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Error("failed to add features: %v", err)
+		os.Exit(1)
+	}
+	printChanges(res, dryRun)
+}
+
+func newFeaturesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "features",
+		Short: "List the optional features for --with and add",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, f := range generator.Features {
+				requires := ""
+				if len(f.Requires) > 0 {
+					requires = " (requires " + strings.Join(f.Requires, ", ") + ")"
+				}
+				fmt.Printf("  %-10s %s%s\n", f.Name, f.Description, requires)
+			}
+		},
+	}
+}
@@ -0,0 +1,151 @@
+// Package accesslog records one log line per HTTP request through the Goob
+// logging contract.
+package accesslog
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/recorder"
+)
+
+// Format selects the access log line layout.
+type Format string
+
+const (
+	FormatOff      Format = "off"      // no access logging
+	FormatCommon   Format = "common"   // NCSA Common Log Format
+	FormatCombined Format = "combined" // Common plus referer and user agent
+	FormatJSON     Format = "json"     // structured fields; JSON with --log-format json
+)
+
+// DefaultRedact lists query parameters whose values are masked by default.
+var DefaultRedact = []string{"token", "csrf_token", "password", "secret", "code", "api_key"}
+
+// Redacted replaces the value of sensitive query parameters.
+const Redacted = "REDACTED"
+
+// ParseFormat converts a flag value into a Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatOff, FormatCommon, FormatCombined, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown access log format %q (want off, common, combined or json)", s)
+}
+
+// Options configures the access log middleware for one listener.
+type Options struct {
+	// Listener names the server ("public", "admin") so traffic can be separated.
+	Listener string
+
+	// Format selects the line layout; FormatOff disables logging.
+	Format Format
+
+	// Redact lists query parameter names whose values are replaced with Redacted.
+	Redact []string
+
+	// Logger receives the lines; defaults to logger.Default.
+	Logger logger.Logger
+}
+
+// Middleware returns access logging middleware configured by opts.
+// Request-scoped fields (such as the request ID) are picked up from the
+// request context, so it should run inside requestid.Middleware.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.Format == FormatOff || opts.Format == "" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if opts.Logger == nil {
+		opts.Logger = logger.Default
+	}
+	redact := make(map[string]bool, len(opts.Redact))
+	for _, name := range opts.Redact {
+		redact[strings.ToLower(name)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := recorder.Wrap(w)
+			next.ServeHTTP(rec, r)
+			elapsed := time.Since(start)
+
+			uri := r.URL.Path
+			if r.URL.RawQuery != "" {
+				uri += "?" + redactQuery(r.URL.RawQuery, redact)
+			}
+			l := logger.With(logger.FromContext(r.Context(), opts.Logger), "listener", opts.Listener)
+
+			switch opts.Format {
+			case FormatJSON:
+				logger.With(l,
+					"method", r.Method,
+					"path", uri,
+					"proto", r.Proto,
+					"status", rec.Status(),
+					"bytes", rec.Bytes(),
+					"duration_ms", float64(elapsed.Microseconds())/1000,
+					"remote_addr", remoteHost(r),
+					"referer", r.Referer(),
+					"user_agent", r.UserAgent(),
+				).Info("http request")
+			default:
+				line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+					remoteHost(r), userOrDash(r), start.Format("02/Jan/2006:15:04:05 -0700"),
+					r.Method, uri, r.Proto, rec.Status(), sizeOrDash(rec.Bytes()))
+				if opts.Format == FormatCombined {
+					line += fmt.Sprintf(` %q %q`, r.Referer(), r.UserAgent())
+				}
+				logger.With(l, "duration", elapsed.String()).Info("%s", line)
+			}
+		})
+	}
+}
+
+// redactQuery masks sensitive parameter values while preserving the order
+// and encoding of the rest of the query string.
+func redactQuery(raw string, redact map[string]bool) string {
+	if len(redact) == 0 {
+		return raw
+	}
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		key, _, hasValue := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && redact[strings.ToLower(name)] {
+			parts[i] = key + "=" + Redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func userOrDash(r *http.Request) string {
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		return u
+	}
+	return "-"
+}
+
+func sizeOrDash(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", n)
+}
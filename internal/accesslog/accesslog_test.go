@@ -0,0 +1,79 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+func serve(t *testing.T, opts Options, target string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	opts.Logger = logger.NewSlogLogger(&buf, logger.FormatJSON, slog.LevelInfo)
+	h := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	return buf.String()
+}
+
+func TestMiddlewareJSON(t *testing.T) {
+	out := serve(t, Options{Listener: "admin", Format: FormatJSON, Redact: DefaultRedact}, "/admin/echo?q=hi&token=s3cr3t")
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(out), &line); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", out, err)
+	}
+	want := map[string]any{
+		"listener":    "admin",
+		"method":      "POST",
+		"path":        "/admin/echo?q=hi&token=REDACTED",
+		"status":      float64(201),
+		"bytes":       float64(5),
+		"remote_addr": "192.0.2.7",
+		"user_agent":  "curl/8.0",
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if _, ok := line["duration_ms"]; !ok {
+		t.Error("missing duration_ms")
+	}
+}
+
+func TestMiddlewareCombined(t *testing.T) {
+	out := serve(t, Options{Listener: "public", Format: FormatCombined, Redact: []string{"Password"}}, "/login?user=a&password=x")
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(out), &line); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", out, err)
+	}
+	msg, _ := line["msg"].(string)
+	if !strings.HasPrefix(msg, "192.0.2.7 - - [") {
+		t.Errorf("unexpected CLF prefix: %q", msg)
+	}
+	if !strings.HasSuffix(msg, `"POST /login?user=a&password=REDACTED HTTP/1.1" 201 5 "" "curl/8.0"`) {
+		t.Errorf("unexpected combined line: %q", msg)
+	}
+	if line["listener"] != "public" {
+		t.Errorf("listener = %v, want public", line["listener"])
+	}
+}
+
+func TestMiddlewareOff(t *testing.T) {
+	if out := serve(t, Options{Format: FormatOff}, "/"); out != "" {
+		t.Errorf("expected no output, got %q", out)
+	}
+}
@@ -0,0 +1,148 @@
+// Package assets layers an on-disk directory over embedded default assets
+// and can write the defaults out so they can be customised.
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/maloquacious/goobtool/internal/fsutil"
+)
+
+// Overlay returns a file system that serves a file from dir when it exists
+// there and from base otherwise. Directory listings merge both. dir is
+// looked up on every Open, so it may be missing, or created and edited
+// while the server runs. An empty dir returns base.
+func Overlay(dir string, base fs.FS) fs.FS {
+	if dir == "" {
+		return base
+	}
+	return &overlayFS{upper: os.DirFS(dir), base: base}
+}
+
+type overlayFS struct {
+	upper fs.FS
+	base  fs.FS
+}
+
+// Open implements fs.FS.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := o.upper.Open(name)
+	if notFound(err) {
+		return o.base.Open(name)
+	} else if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f, nil
+	}
+	entries, err := o.ReadDir(name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &dirFile{File: f, entries: entries}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. Entries in the overlay directory hide
+// base entries with the same name.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, err := fs.ReadDir(o.upper, name)
+	if err != nil && !notFound(err) {
+		return nil, err
+	}
+	base, baseErr := fs.ReadDir(o.base, name)
+	if baseErr != nil && !notFound(baseErr) {
+		return nil, baseErr
+	}
+	if err != nil && baseErr != nil {
+		return nil, baseErr
+	}
+
+	entries := upper
+	for _, e := range base {
+		if !slices.ContainsFunc(upper, func(u fs.DirEntry) bool { return u.Name() == e.Name() }) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// notFound reports whether err means the overlay does not have the path,
+// including when a parent is a file rather than a directory.
+func notFound(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// dirFile is an overlay directory whose listing is the merged one.
+type dirFile struct {
+	fs.File
+	entries []fs.DirEntry
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		rest := d.entries
+		d.entries = nil
+		return rest, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	batch := d.entries[:n]
+	d.entries = d.entries[n:]
+	return batch, nil
+}
+
+// Extract copies every file in each tree into the subdirectory of dir
+// named by its key, creating directories as needed, and returns the paths
+// written relative to dir. An empty key extracts into dir itself. Unless
+// force is set it writes nothing if any file already exists, returning a
+// *fsutil.ExistsError.
+func Extract(dir string, trees map[string]fs.FS, force bool) ([]string, error) {
+	var files []fsutil.File
+	for _, prefix := range slices.Sorted(maps.Keys(trees)) {
+		fsys := trees[prefix]
+		err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			files = append(files, fsutil.File{Path: path.Join(prefix, name), Data: data})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read assets: %w", err)
+		}
+	}
+	if err := fsutil.Write(dir, files, force); err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths, nil
+}
@@ -0,0 +1,179 @@
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maloquacious/goobtool/internal/fsutil"
+)
+
+var base = fstest.MapFS{
+	"index.html":    {Data: []byte("embedded index")},
+	"install.html":  {Data: []byte("embedded install")},
+	"css/site.css":  {Data: []byte("embedded css")},
+	"css/print.css": {Data: []byte("embedded print")},
+}
+
+func writeFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) failed: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "index.html", "custom index")
+	writeFile(t, dir, "css/site.css", "custom css")
+	writeFile(t, dir, "css/extra.css", "extra css")
+	fsys := Overlay(dir, base)
+
+	for name, want := range map[string]string{
+		"index.html":    "custom index",
+		"install.html":  "embedded install",
+		"css/site.css":  "custom css",
+		"css/print.css": "embedded print",
+		"css/extra.css": "extra css",
+	} {
+		if got := readFile(t, fsys, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, "css")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"extra.css", "print.css", "site.css"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir = %v, want %v", names, want)
+	}
+
+	if _, err := fsys.Open("missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing.html) = %v, want ErrNotExist", err)
+	}
+	if _, err := fsys.Open("../secret"); err == nil {
+		t.Error("Open(../secret) succeeded")
+	}
+	if err := fstest.TestFS(fsys, "index.html", "install.html", "css/site.css", "css/print.css", "css/extra.css"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOverlayMissingDir(t *testing.T) {
+	fsys := Overlay(filepath.Join(t.TempDir(), "nope"), base)
+	if got := readFile(t, fsys, "index.html"); got != "embedded index" {
+		t.Errorf("index.html = %q, want the embedded copy", got)
+	}
+
+	// files added after startup are picked up
+	dir := t.TempDir()
+	fsys = Overlay(dir, base)
+	writeFile(t, dir, "index.html", "late index")
+	if got := readFile(t, fsys, "index.html"); got != "late index" {
+		t.Errorf("index.html = %q, want the overlay copy", got)
+	}
+}
+
+func TestOverlayFileServer(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "css/site.css", "custom css")
+	h := http.FileServerFS(Overlay(dir, base))
+
+	for path, want := range map[string]string{
+		"/css/site.css":  "custom css",
+		"/css/print.css": "embedded print",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := Extract(dir, map[string]fs.FS{"": base}, false)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(paths) != len(base) {
+		t.Errorf("Extract wrote %v, want %d files", paths, len(base))
+	}
+	if got := readFile(t, os.DirFS(dir), "css/site.css"); got != "embedded css" {
+		t.Errorf("css/site.css = %q", got)
+	}
+
+	writeFile(t, dir, "index.html", "custom index")
+	var exists *fsutil.ExistsError
+	if _, err := Extract(dir, map[string]fs.FS{"": base}, false); !errors.As(err, &exists) || len(exists.Paths) != len(base) {
+		t.Fatalf("second Extract = %v, want ExistsError for every file", err)
+	}
+	if got := readFile(t, os.DirFS(dir), "index.html"); got != "custom index" {
+		t.Errorf("refused Extract overwrote index.html: %q", got)
+	}
+
+	if _, err := Extract(dir, map[string]fs.FS{"": base}, true); err != nil {
+		t.Fatalf("Extract with force failed: %v", err)
+	}
+	if got := readFile(t, os.DirFS(dir), "index.html"); got != "embedded index" {
+		t.Errorf("forced Extract left index.html = %q", got)
+	}
+}
+
+func TestExtractTrees(t *testing.T) {
+	dir := t.TempDir()
+	trees := map[string]fs.FS{
+		"public":    base,
+		"templates": fstest.MapFS{"pages/index.html": {Data: []byte("page")}},
+	}
+	paths, err := Extract(dir, trees, false)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(paths) != len(base)+1 {
+		t.Errorf("Extract wrote %v, want %d files", paths, len(base)+1)
+	}
+	if got := readFile(t, os.DirFS(dir), "public/css/site.css"); got != "embedded css" {
+		t.Errorf("public/css/site.css = %q", got)
+	}
+	if got := readFile(t, os.DirFS(dir), "templates/pages/index.html"); got != "page" {
+		t.Errorf("templates/pages/index.html = %q", got)
+	}
+
+	// a conflict in one tree keeps the other from being written
+	if err := os.RemoveAll(filepath.Join(dir, "public")); err != nil {
+		t.Fatal(err)
+	}
+	var exists *fsutil.ExistsError
+	if _, err := Extract(dir, trees, false); !errors.As(err, &exists) || len(exists.Paths) != 1 {
+		t.Fatalf("second Extract = %v, want ExistsError for the template", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "public")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("refused Extract wrote public assets: %v", err)
+	}
+}
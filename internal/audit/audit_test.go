@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func chain(n int) []Record {
+	prev := GenesisHash
+	records := make([]Record, n)
+	for i := range records {
+		rec := Record{
+			Seq:    int64(i + 1),
+			Time:   time.Unix(1700000000+int64(i), 0).UTC(),
+			Actor:  "cli:test",
+			Action: ActionServerShutdown,
+		}
+		rec.PrevHash = prev
+		rec.Hash = ComputeHash(prev, rec)
+		prev = rec.Hash
+		records[i] = rec
+	}
+	return records
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func([]Record) []Record
+		ok       bool
+		brokenAt int64
+	}{
+		{"intact", func(r []Record) []Record { return r }, true, 0},
+		{"empty", func(r []Record) []Record { return nil }, true, 0},
+		{"edited field", func(r []Record) []Record { r[1].Actor = "mallory"; return r }, false, 2},
+		{"deleted record", func(r []Record) []Record { return append(r[:1], r[2:]...) }, false, 2},
+		{"truncated head", func(r []Record) []Record { return r[1:] }, false, 1},
+		{"rehashed edit", func(r []Record) []Record {
+			r[1].Target = "x"
+			r[1].Hash = ComputeHash(r[1].PrevHash, r[1])
+			return r
+		}, false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := VerifyChain(tt.tamper(chain(4)))
+			if res.OK != tt.ok {
+				t.Fatalf("OK = %v, want %v (%+v)", res.OK, tt.ok, res)
+			}
+			if !tt.ok && res.BrokenAt != tt.brokenAt {
+				t.Errorf("BrokenAt = %d, want %d (%s)", res.BrokenAt, tt.brokenAt, res.Reason)
+			}
+		})
+	}
+}
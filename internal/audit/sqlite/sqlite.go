@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/audit"
+)
+
+// AuditLog implements the audit.Log interface on a SQLite connection
+// shared with the main datastore.
+type AuditLog struct {
+	db *sql.DB
+	mu sync.Mutex // serializes Append so each record links to the latest hash
+}
+
+// New creates a new AuditLog using an already opened database.
+func New(db *sql.DB) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+// InitSchema creates the audit table and its append-only triggers.
+func (a *AuditLog) InitSchema() error {
+	if _, err := a.db.Exec(auditSchema); err != nil {
+		return fmt.Errorf("failed to create audit schema: %w", err)
+	}
+	return nil
+}
+
+// Append links rec to the end of the chain and stores it.
+func (a *AuditLog) Append(rec audit.Record) (audit.Record, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return audit.Record{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	prevHash := audit.GenesisHash
+	err = tx.QueryRow(`SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return audit.Record{}, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+
+	rec.Seq = lastSeq + 1
+	rec.Time = time.Now().UTC()
+	rec.PrevHash = prevHash
+	rec.Hash = audit.ComputeHash(prevHash, rec)
+
+	_, err = tx.Exec(`INSERT INTO audit_log (seq, ts, actor, action, target, details, request_id, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.Seq, rec.Time.UnixNano(), rec.Actor, rec.Action, rec.Target, rec.Details, rec.RequestID, rec.PrevHash, rec.Hash)
+	if err != nil {
+		return audit.Record{}, fmt.Errorf("failed to append audit record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return audit.Record{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns records matching f in chain order.
+func (a *AuditLog) List(f audit.Filter) ([]audit.Record, error) {
+	var where []string
+	var args []any
+	if f.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, f.Action)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "ts >= ?")
+		args = append(args, f.Since.UnixNano())
+	}
+
+	query := `SELECT seq, ts, actor, action, target, details, request_id, prev_hash, hash FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if f.Limit > 0 {
+		// newest N, returned oldest first
+		query = `SELECT * FROM (` + query + ` ORDER BY seq DESC LIMIT ?) ORDER BY seq`
+		args = append(args, f.Limit)
+	} else {
+		query += " ORDER BY seq"
+	}
+
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []audit.Record
+	for rows.Next() {
+		var rec audit.Record
+		var ts int64
+		if err := rows.Scan(&rec.Seq, &ts, &rec.Actor, &rec.Action, &rec.Target, &rec.Details, &rec.RequestID, &rec.PrevHash, &rec.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit record: %w", err)
+		}
+		rec.Time = time.Unix(0, ts).UTC()
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	return records, nil
+}
+
+// Verify walks the whole chain.
+func (a *AuditLog) Verify() (audit.VerifyResult, error) {
+	records, err := a.List(audit.Filter{})
+	if err != nil {
+		return audit.VerifyResult{}, err
+	}
+	return audit.VerifyChain(records), nil
+}
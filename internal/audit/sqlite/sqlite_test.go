@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/audit"
+	_ "modernc.org/sqlite"
+)
+
+func openTestLog(t *testing.T) (*AuditLog, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "audit.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	a := New(db)
+	if err := a.InitSchema(); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	return a, db
+}
+
+func TestAuditLog(t *testing.T) {
+	a, db := openTestLog(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := a.Append(audit.Record{Actor: "cli:test", Action: audit.ActionServerShutdown}); err != nil {
+				t.Errorf("Append failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := a.Append(audit.Record{Actor: "cli:test", Action: audit.ActionDBUpgrade, Details: `{"to":"0.2"}`}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	res, err := a.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !res.OK || res.Records != 11 {
+		t.Fatalf("Verify = %+v, want 11 intact records", res)
+	}
+
+	recent, err := a.List(audit.Filter{Limit: 3})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(recent) != 3 || recent[0].Seq != 9 || recent[2].Seq != 11 {
+		t.Errorf("List(Limit: 3) returned seqs %v", recent)
+	}
+	upgrades, _ := a.List(audit.Filter{Action: audit.ActionDBUpgrade})
+	if len(upgrades) != 1 || upgrades[0].Details != `{"to":"0.2"}` {
+		t.Errorf("List(Action) = %+v", upgrades)
+	}
+
+	if _, err := db.Exec(`UPDATE audit_log SET actor = 'mallory' WHERE seq = 2`); err == nil {
+		t.Error("UPDATE on audit_log should be rejected")
+	}
+	if _, err := db.Exec(`DELETE FROM audit_log WHERE seq = 2`); err == nil {
+		t.Error("DELETE on audit_log should be rejected")
+	}
+
+	// Bypass the triggers the way someone editing the file would.
+	if _, err := db.Exec(`DROP TRIGGER audit_log_no_update`); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE audit_log SET actor = 'mallory' WHERE seq = 5`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	res, err = a.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if res.OK || res.BrokenAt != 5 {
+		t.Errorf("Verify after tampering = %+v, want broken at 5", res)
+	}
+}
@@ -0,0 +1,68 @@
+// Package buildinfo reports how the running binary was built, from the
+// metadata the Go toolchain embeds (runtime/debug.ReadBuildInfo).
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/maloquacious/semver"
+)
+
+// Info describes a build.
+type Info struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	Revision  string `json:"revision,omitempty"`
+	Dirty     bool   `json:"dirty"`
+
+	// BuildTime is when the binary was built. The toolchain does not
+	// record it, so it is empty unless stamped at link time.
+	BuildTime string `json:"buildTime,omitempty"`
+
+	// CommitTime is the VCS time of Revision.
+	CommitTime string `json:"commitTime,omitempty"`
+}
+
+// Read returns the build information for an application at version v.
+// When v has no build metadata the short VCS revision is used, with a
+// "-dirty" suffix for builds from a modified tree. buildTime is typically
+// set with -ldflags "-X main.buildDate=..."; the VCS commit time is kept
+// separately in CommitTime.
+func Read(v semver.Version, buildTime string) Info {
+	bi, _ := debug.ReadBuildInfo() // nil when not built with module support
+	return fromBuildInfo(bi, v, buildTime)
+}
+
+func fromBuildInfo(bi *debug.BuildInfo, v semver.Version, buildTime string) Info {
+	info := Info{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		BuildTime: buildTime,
+	}
+	if bi != nil {
+		if bi.GoVersion != "" {
+			info.GoVersion = bi.GoVersion
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.modified":
+				info.Dirty = s.Value == "true"
+			case "vcs.time":
+				info.CommitTime = s.Value
+			}
+		}
+	}
+
+	if v.Build == "" && info.Revision != "" {
+		v.Build = info.Revision[:min(7, len(info.Revision))]
+		if info.Dirty {
+			v.Build += "-dirty"
+		}
+	}
+	info.Version = v.String()
+	return info
+}
@@ -0,0 +1,38 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/maloquacious/semver"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	v := semver.Version{Minor: 2, PreRelease: "alpha"}
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.25.2",
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2025-10-19T12:00:00Z"},
+			{Key: "vcs.modified", Value: "false"},
+		},
+	}
+
+	info := fromBuildInfo(bi, v, "")
+	if info.Version != "0.2.0-alpha+0123456" || info.GoVersion != "go1.25.2" || info.Dirty || info.BuildTime != "" || info.CommitTime != "2025-10-19T12:00:00Z" {
+		t.Errorf("clean build: %+v", info)
+	}
+
+	bi.Settings[2].Value = "true"
+	info = fromBuildInfo(bi, v, "2025-10-20T08:00:00Z")
+	if info.Version != "0.2.0-alpha+0123456-dirty" || !info.Dirty || info.BuildTime != "2025-10-20T08:00:00Z" || info.CommitTime != "2025-10-19T12:00:00Z" {
+		t.Errorf("dirty build with a link-time date: %+v", info)
+	}
+
+	// explicit build metadata wins; no build info at all still reports Go
+	v.Build = "release"
+	info = fromBuildInfo(nil, v, "")
+	if info.Version != "0.2.0-alpha+release" || info.GoVersion == "" || info.Revision != "" {
+		t.Errorf("without build info: %+v", info)
+	}
+}
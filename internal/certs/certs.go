@@ -0,0 +1,142 @@
+// Package certs loads TLS certificates from PEM files, reloads them without
+// a restart, and creates self-signed certificates for development. It never
+// talks to an ACME server; certificates are provisioned by the operator.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+// Reloader serves a certificate and key pair from disk and swaps in a new
+// pair when Reload is called or Watch sees the files change. A pair that
+// fails to load is reported and the previous one stays in use.
+type Reloader struct {
+	certFile, keyFile string
+	log               logger.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // latest modification time of the two files
+}
+
+// NewReloader loads certFile and keyFile.
+func NewReloader(certFile, keyFile string, log logger.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, log: log}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again.
+func (r *Reloader) Reload() error {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	logger.With(r.log, "file", r.certFile, "subject", leaf.Subject.String(), "not_after", leaf.NotAfter.UTC().Format(time.RFC3339)).Info("tls certificate loaded")
+	if time.Until(leaf.NotAfter) < 14*24*time.Hour {
+		logger.With(r.log, "not_after", leaf.NotAfter.UTC().Format(time.RFC3339)).Warn("tls certificate expires soon")
+	}
+	return nil
+}
+
+func (r *Reloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Watch polls the files every interval and reloads them when either has
+// been modified, until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		modTime, err := r.filesModTime()
+		if err != nil {
+			// mid-rotation; try again on the next tick
+			continue
+		}
+		r.mu.RLock()
+		changed := !modTime.Equal(r.modTime)
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			r.log.Error("tls certificate reload failed, keeping the current one: %v", err)
+			// do not retry the same broken files on every tick
+			r.mu.Lock()
+			r.modTime = modTime
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Certificate returns the certificate in use.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// Config returns a server configuration with modern defaults that gets its
+// certificate from r: TLS 1.2 or later, and for TLS 1.2 only forward-secret
+// AEAD cipher suites.
+func Config(r *Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		GetCertificate:   r.GetCertificate,
+		CurvePreferences: []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
@@ -0,0 +1,182 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+// writePair writes a new self-signed pair for host into dir.
+func writePair(t *testing.T, dir, host string) (certFile, keyFile string) {
+	t.Helper()
+	certPEM, keyPEM, err := SelfSigned([]string{host}, time.Hour*24*30)
+	if err != nil {
+		t.Fatalf("SelfSigned failed: %v", err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func testLogger() (logger.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return logger.NewSlogLogger(&buf, logger.FormatText, slog.LevelInfo), &buf
+}
+
+func TestSelfSigned(t *testing.T) {
+	certPEM, keyPEM, err := SelfSigned([]string{"localhost", "127.0.0.1", "::1"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("SelfSigned failed: %v", err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Error(err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Error(err)
+	}
+	if err := leaf.VerifyHostname("example.com"); err == nil {
+		t.Error("certificate is valid for example.com")
+	}
+	if leaf.IsCA || time.Until(leaf.NotAfter) > 25*time.Hour {
+		t.Errorf("IsCA = %v, NotAfter = %v", leaf.IsCA, leaf.NotAfter)
+	}
+
+	if _, _, err := SelfSigned(nil, time.Hour); err == nil {
+		t.Error("SelfSigned without hosts succeeded")
+	}
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "one.test")
+	log, buf := testLogger()
+
+	r, err := NewReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("NewReloader failed: %v", err)
+	}
+	if cn := r.Certificate().Leaf.Subject.CommonName; cn != "one.test" {
+		t.Fatalf("CommonName = %q", cn)
+	}
+
+	writePair(t, dir, "two.test")
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if cn := r.Certificate().Leaf.Subject.CommonName; cn != "two.test" {
+		t.Errorf("after Reload CommonName = %q", cn)
+	}
+
+	// a broken pair is rejected and the current one stays
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("Reload of a broken pair succeeded")
+	}
+	if cn := r.Certificate().Leaf.Subject.CommonName; cn != "two.test" {
+		t.Errorf("after a failed Reload CommonName = %q", cn)
+	}
+
+	if _, err := NewReloader(filepath.Join(dir, "missing.pem"), keyFile, log); err == nil {
+		t.Error("NewReloader with a missing file succeeded")
+	}
+	if buf.Len() == 0 {
+		t.Error("nothing was logged")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "one.test")
+	log, _ := testLogger()
+	r, err := NewReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("NewReloader failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	writePair(t, dir, "two.test")
+	// make the change visible on file systems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Certificate().Leaf.Subject.CommonName != "two.test" {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not reload the changed files")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "localhost")
+	log, _ := testLogger()
+	r, err := NewReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("NewReloader failed: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", Config(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.(*tls.Conn).Handshake()
+			c.Close()
+		}
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(r.Certificate().Leaf)
+	dial := func(cfg *tls.Config) error {
+		cfg.RootCAs, cfg.ServerName = pool, "localhost"
+		c, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", ln.Addr().String(), cfg)
+		if err == nil {
+			c.Close()
+		}
+		return err
+	}
+	if err := dial(&tls.Config{}); err != nil {
+		t.Errorf("modern client failed: %v", err)
+	}
+	if err := dial(&tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}); err == nil {
+		t.Error("TLS 1.1 client connected")
+	}
+	if err := dial(&tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA}}); err == nil {
+		t.Error("CBC cipher suite negotiated")
+	}
+}
@@ -0,0 +1,280 @@
+// Package csrf implements the Goob CSRF protection contract.
+//
+// The default implementation uses the double-submit cookie pattern: a token
+// is stored in a cookie and must be echoed back in a request header (or form
+// field) on every state-changing request. Tokens are HMAC-signed with a server
+// secret, so only tokens this server issued are accepted. When
+// Options.SessionID is set they are also bound to the caller's session ID, and
+// a token planted from another session is rejected even when cookie and header
+// match; without it any token the server issued is valid for any client.
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+const (
+	DefaultCookieName = "goob_csrf"
+	DefaultHeaderName = "X-CSRF-Token"
+	DefaultFormField  = "csrf_token"
+
+	nonceLen = 16
+)
+
+// CSRFMiddleware defines the Goob CSRF contract.
+// Implementations must be safe for concurrent use.
+type CSRFMiddleware interface {
+	// Protect rejects unsafe requests (anything other than GET, HEAD,
+	// OPTIONS, TRACE) that lack a valid token, and makes the current token
+	// available to downstream handlers via Token.
+	Protect(next http.Handler) http.Handler
+
+	// Rotate issues a fresh token, replacing any existing one.
+	// Call it on login and privilege changes.
+	Rotate(w http.ResponseWriter, r *http.Request) (string, error)
+}
+
+// Options configures the double-submit implementation.
+// Zero values select the defaults.
+type Options struct {
+	// Secret signs tokens; a random 32-byte secret is generated when empty.
+	Secret []byte
+
+	// CookieName, HeaderName and FormField name the token carriers.
+	CookieName string
+	HeaderName string
+	FormField  string
+
+	// SessionID returns the caller's session ID, binding tokens to it.
+	// Requests without a session share the empty ID.
+	SessionID func(*http.Request) string
+
+	// ErrorHandler writes the rejection response; defaults to a JSON 403.
+	ErrorHandler http.Handler
+
+	// Exempt, if set, lets requests it returns true for through without a
+	// token, for endpoints that receive browser-generated posts such as
+	// CSP violation reports. Exempt requests are not issued a token.
+	Exempt func(*http.Request) bool
+}
+
+// DoubleSubmit is the default CSRFMiddleware implementation.
+type DoubleSubmit struct {
+	secret       []byte
+	cookieName   string
+	headerName   string
+	formField    string
+	sessionID    func(*http.Request) string
+	errorHandler http.Handler
+	exempt       func(*http.Request) bool
+}
+
+// New creates a DoubleSubmit middleware from opts.
+func New(opts Options) (*DoubleSubmit, error) {
+	secret := opts.Secret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate csrf secret: %w", err)
+		}
+	}
+	d := &DoubleSubmit{
+		secret:       secret,
+		cookieName:   opts.CookieName,
+		headerName:   opts.HeaderName,
+		formField:    opts.FormField,
+		sessionID:    opts.SessionID,
+		errorHandler: opts.ErrorHandler,
+		exempt:       opts.Exempt,
+	}
+	if d.cookieName == "" {
+		d.cookieName = DefaultCookieName
+	}
+	if d.headerName == "" {
+		d.headerName = DefaultHeaderName
+	}
+	if d.formField == "" {
+		d.formField = DefaultFormField
+	}
+	if d.sessionID == nil {
+		d.sessionID = func(*http.Request) string { return "" }
+	}
+	if d.errorHandler == nil {
+		d.errorHandler = http.HandlerFunc(defaultErrorHandler)
+	}
+	return d, nil
+}
+
+// HeaderName returns the request header clients must echo the token in.
+func (d *DoubleSubmit) HeaderName() string {
+	return d.headerName
+}
+
+// Protect implements CSRFMiddleware.
+func (d *DoubleSubmit) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.exempt != nil && d.exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sid := d.sessionID(r)
+		token := ""
+		if c, err := r.Cookie(d.cookieName); err == nil && d.valid(c.Value, sid) {
+			token = c.Value
+		}
+
+		if !isSafeMethod(r.Method) {
+			sent := r.Header.Get(d.headerName)
+			if sent == "" {
+				sent = r.PostFormValue(d.formField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				d.errorHandler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if token == "" {
+			var err error
+			if token, err = d.issue(w, r, sid); err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(WithToken(r.Context(), d.headerName, token)))
+	})
+}
+
+// Rotate implements CSRFMiddleware.
+func (d *DoubleSubmit) Rotate(w http.ResponseWriter, r *http.Request) (string, error) {
+	return d.issue(w, r, d.sessionID(r))
+}
+
+// Handler returns a JSON endpoint that reports the current token, for
+// clients (HTMX, Alpine, the admin CLI) that cannot read it from a template.
+// It must be mounted behind Protect.
+func (d *DoubleSubmit) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"token":  Token(r),
+			"header": d.headerName,
+		})
+	})
+}
+
+// issue creates a new signed token, sets the cookie and returns the token.
+func (d *DoubleSubmit) issue(w http.ResponseWriter, r *http.Request, sid string) (string, error) {
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate csrf nonce: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(append(nonce, d.sign(nonce, sid)...))
+	http.SetCookie(w, &http.Cookie{
+		Name:     d.cookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// valid reports whether token was signed by this server for session sid.
+func (d *DoubleSubmit) valid(token, sid string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != nonceLen+sha256.Size {
+		return false
+	}
+	return hmac.Equal(raw[nonceLen:], d.sign(raw[:nonceLen], sid))
+}
+
+func (d *DoubleSubmit) sign(nonce []byte, sid string) []byte {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(sid))
+	mac.Write([]byte{0})
+	mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+type tokenKey struct{}
+
+type tokenValue struct {
+	header string
+	token  string
+}
+
+// WithToken returns a copy of ctx carrying the current CSRF token and the
+// header clients must send it in. CSRFMiddleware implementations call this
+// before invoking the next handler.
+func WithToken(ctx context.Context, header, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, tokenValue{header: header, token: token})
+}
+
+// Token returns the CSRF token for the request, or "" if the request
+// did not pass through a CSRFMiddleware.
+func Token(r *http.Request) string {
+	v, _ := r.Context().Value(tokenKey{}).(tokenValue)
+	return v.token
+}
+
+// HXHeaders renders an hx-headers attribute carrying the request's token,
+// for use in templates as <body {{ csrfHeaders }}> so that every HTMX
+// request issued from the page includes it.
+func HXHeaders(r *http.Request) template.HTMLAttr {
+	return template.HTMLAttr(`hx-headers='` + template.HTMLEscapeString(HXHeadersJSON(r)) + `'`)
+}
+
+// HXHeadersJSON returns the value of an hx-headers attribute carrying the
+// request's token, for templates that write the attribute themselves:
+// <body hx-headers='{{ csrfHeaders .Request }}'>. html/template escapes it.
+func HXHeadersJSON(r *http.Request) string {
+	v, _ := r.Context().Value(tokenKey{}).(tokenValue)
+	if v.header == "" {
+		v.header = DefaultHeaderName
+	}
+	b, _ := json.Marshal(map[string]string{v.header: v.token})
+	return string(b)
+}
+
+// FuncMap returns template helpers bound to r: csrfToken and csrfHeaders.
+func FuncMap(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken":   func() string { return Token(r) },
+		"csrfHeaders": func() template.HTMLAttr { return HXHeaders(r) },
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// isSecure reports whether the request arrived over TLS, directly or via a
+// proxy that set X-Forwarded-Proto: https.
+func isSecure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   "forbidden",
+		"message": "missing or invalid CSRF token",
+	})
+}
@@ -0,0 +1,148 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestMiddleware(t *testing.T, sid *string) *DoubleSubmit {
+	t.Helper()
+	d, err := New(Options{
+		Secret:    []byte("test-secret"),
+		SessionID: func(*http.Request) string { return *sid },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return d
+}
+
+// fetchToken performs a safe request and returns the issued cookie.
+func fetchToken(t *testing.T, h http.Handler) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultCookieName {
+			return c
+		}
+	}
+	t.Fatal("no csrf cookie issued on safe request")
+	return nil
+}
+
+func TestProtect(t *testing.T) {
+	sid := "session-a"
+	d := newTestMiddleware(t, &sid)
+	var seen string
+	h := d.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = Token(r)
+	}))
+
+	cookie := fetchToken(t, h)
+	if seen != cookie.Value {
+		t.Errorf("Token(r) = %q, want cookie value %q", seen, cookie.Value)
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie missing secure defaults: %+v", cookie)
+	}
+
+	tests := []struct {
+		name       string
+		sid        string
+		cookie     string
+		header     string
+		form       string
+		wantStatus int
+	}{
+		{"valid header", "session-a", cookie.Value, cookie.Value, "", http.StatusOK},
+		{"valid form field", "session-a", cookie.Value, "", cookie.Value, http.StatusOK},
+		{"missing token", "session-a", cookie.Value, "", "", http.StatusForbidden},
+		{"missing cookie", "session-a", "", cookie.Value, "", http.StatusForbidden},
+		{"mismatched token", "session-a", cookie.Value, "bogus", "", http.StatusForbidden},
+		{"forged cookie", "session-a", "bogus", "bogus", "", http.StatusForbidden},
+		{"other session", "session-b", cookie.Value, cookie.Value, "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sid = tt.sid
+			var body *strings.Reader
+			if tt.form != "" {
+				body = strings.NewReader(DefaultFormField + "=" + tt.form)
+			} else {
+				body = strings.NewReader("")
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", body)
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: DefaultCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(DefaultHeaderName, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	sid := "session-a"
+	d := newTestMiddleware(t, &sid)
+	old := fetchToken(t, d.Protect(http.NotFoundHandler()))
+
+	rec := httptest.NewRecorder()
+	token, err := d.Rotate(rec, httptest.NewRequest(http.MethodPost, "/login", nil))
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if token == "" || token == old.Value {
+		t.Errorf("Rotate returned %q, want a new token", token)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != token {
+		t.Errorf("Rotate did not set the new cookie: %+v", cookies)
+	}
+}
+
+func TestExempt(t *testing.T) {
+	d, err := New(Options{Exempt: func(r *http.Request) bool { return r.URL.Path == "/report" }})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	h := d.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for path, want := range map[string]int{"/report": http.StatusNoContent, "/other": http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != want {
+			t.Errorf("POST %s = %d, want %d", path, rec.Code, want)
+		}
+		if path == "/report" && len(rec.Result().Cookies()) != 0 {
+			t.Error("exempt request was issued a token")
+		}
+	}
+}
+
+func TestHXHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(WithToken(req.Context(), DefaultHeaderName, "abc"))
+	got := string(HXHeaders(req))
+	want := `hx-headers='{&#34;X-CSRF-Token&#34;:&#34;abc&#34;}'`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := HXHeadersJSON(req), `{"X-CSRF-Token":"abc"}`; got != want {
+		t.Errorf("HXHeadersJSON = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Package fsutil writes sets of files into a directory without clobbering
+// existing ones unless asked to.
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File is one file to write. Path is slash-separated and relative to the
+// target directory.
+type File struct {
+	Path string
+	Data []byte
+}
+
+// ExistsError is returned by Write when files are in the way.
+type ExistsError struct {
+	Paths []string
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("%d file(s) already exist (use --force to overwrite): %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// Write writes files under dir, creating directories as needed. Unless
+// force is set it checks every path first and writes nothing if any file
+// already exists.
+func Write(dir string, files []File, force bool) error {
+	for _, f := range files {
+		if !fs.ValidPath(f.Path) || f.Path == "." {
+			return fmt.Errorf("refusing to write outside %s: %q", dir, f.Path)
+		}
+	}
+
+	if !force {
+		var existing []string
+		for _, f := range files {
+			_, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(f.Path)))
+			if err == nil {
+				existing = append(existing, f.Path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to check %s: %w", f.Path, err)
+			}
+		}
+		if len(existing) > 0 {
+			return &ExistsError{Paths: existing}
+		}
+	}
+
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", f.Path, err)
+		}
+		if err := os.WriteFile(target, f.Data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	files := []File{{Path: "a.txt", Data: []byte("new a")}, {Path: "sub/b.txt", Data: []byte("new b")}}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Write(dir, files, false)
+	var exists *ExistsError
+	if !errors.As(err, &exists) || len(exists.Paths) != 1 || exists.Paths[0] != "a.txt" {
+		t.Fatalf("Write = %v, want ExistsError for a.txt", err)
+	}
+	// nothing is written when refusing
+	if _, err := os.Stat(filepath.Join(dir, "sub", "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("sub/b.txt written despite the conflict: %v", err)
+	}
+
+	if err := Write(dir, files, true); err != nil {
+		t.Fatalf("Write with force failed: %v", err)
+	}
+	for _, f := range files {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil || string(got) != string(f.Data) {
+			t.Errorf("%s = %q, %v; want %q", f.Path, got, err, f.Data)
+		}
+	}
+
+	if err := Write(dir, []File{{Path: "../escape.txt"}}, true); err == nil {
+		t.Error("Write outside dir succeeded")
+	}
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Feature is an optional subsystem. Its files live in skeleton/<Name>;
+// files in skeleton/base wire it in with {{if .Has "<Name>"}}.
+type Feature struct {
+	Name        string
+	Description string
+	Requires    []string
+}
+
+// Features lists the optional subsystems in the order they are wired.
+var Features = []Feature{
+	{Name: "sessions", Description: "server-side sessions with idle and absolute timeouts"},
+	{Name: "csrf", Description: "per-session CSRF tokens checked on unsafe requests", Requires: []string{"sessions"}},
+	{Name: "rbac", Description: "roles, permissions and a Require middleware", Requires: []string{"sessions"}},
+	{Name: "postgres", Description: "PostgreSQL datastore selected with --postgres-dsn"},
+}
+
+// baseDir holds the skeleton files every project gets.
+const baseDir = "base"
+
+func lookupFeature(name string) (Feature, bool) {
+	i := slices.IndexFunc(Features, func(f Feature) bool { return f.Name == name })
+	if i < 0 {
+		return Feature{}, false
+	}
+	return Features[i], true
+}
+
+// ResolveFeatures adds the dependencies of names and returns them in
+// Features order without duplicates.
+func ResolveFeatures(names []string) ([]string, error) {
+	want := make(map[string]bool)
+	var add func(name, from string) error
+	add = func(name, from string) error {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" || want[name] {
+			return nil
+		}
+		f, ok := lookupFeature(name)
+		if !ok {
+			if from != "" {
+				return fmt.Errorf("feature %q requires unknown feature %q", from, name)
+			}
+			return fmt.Errorf("unknown feature %q (available: %s)", name, strings.Join(featureNames(), ", "))
+		}
+		want[name] = true
+		for _, dep := range f.Requires {
+			if err := add(dep, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range names {
+		if err := add(name, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved := []string{}
+	for _, f := range Features {
+		if want[f.Name] {
+			resolved = append(resolved, f.Name)
+		}
+	}
+	return resolved, nil
+}
+
+func featureNames() []string {
+	names := make([]string, len(Features))
+	for i, f := range Features {
+		names[i] = f.Name
+	}
+	return names
+}
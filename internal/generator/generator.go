@@ -0,0 +1,261 @@
+// Package generator renders the embedded Goobergine application skeleton
+// into a new project directory.
+//
+// The skeleton is split into skeleton/base, which every project gets, and
+// one directory per optional Feature. Files ending in ".tmpl" are rendered
+// with text/template and written without the suffix; everything else is
+// copied as is, so the skeleton's own html/template pages keep their
+// actions. Paths are rendered too, which is how cmd/{{.BinaryName}} becomes
+// cmd/widgets.
+package generator
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/maloquacious/goobtool/internal/fsutil"
+)
+
+//go:embed all:skeleton
+var skeletonFS embed.FS
+
+const (
+	skeletonRoot = "skeleton"
+	templateExt  = ".tmpl"
+
+	// GoVersion is the go directive written to generated go.mod files.
+	GoVersion = "1.25"
+
+	// TemplateVersion identifies the skeleton. Bump it whenever the
+	// rendered output changes so goobtool update can tell projects apart.
+	TemplateVersion = "0.2.0"
+)
+
+// Defaults for Config fields left empty.
+const (
+	DefaultPort      = 8080
+	DefaultAdminPort = 8383
+)
+
+// Config holds the template fields for a generated project.
+type Config struct {
+	Module     string `json:"module"`     // Go module path, e.g. github.com/acme/widgets
+	AppName    string `json:"appName"`    // human-readable name shown in pages and logs
+	BinaryName string `json:"binaryName"` // cmd/ directory and executable name
+	Port       int    `json:"port"`       // default public HTTP port
+	AdminPort  int    `json:"adminPort"`  // default loopback admin port
+	PkgPrefix  string `json:"pkgPrefix"`  // lowercase prefix for cookies, env vars and metrics
+
+	Features []string `json:"features"` // optional subsystems; see Features
+}
+
+var (
+	modulePathRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._~-]*(/[A-Za-z0-9._~-]+)*$`)
+	majorRE      = regexp.MustCompile(`^v[0-9]+$`)
+	binaryNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	pkgPrefixRE  = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+)
+
+// SetDefaults fills empty fields from the module path: the binary is named
+// after the last path element (skipping a /vN suffix), the app after the
+// binary, and the prefix is the binary name reduced to [a-z0-9].
+func (c *Config) SetDefaults() {
+	if c.BinaryName == "" {
+		elems := strings.Split(c.Module, "/")
+		name := elems[len(elems)-1]
+		if len(elems) > 1 && majorRE.MatchString(name) {
+			name = elems[len(elems)-2]
+		}
+		c.BinaryName = name
+	}
+	if c.AppName == "" {
+		c.AppName = c.BinaryName
+	}
+	if c.PkgPrefix == "" {
+		var b strings.Builder
+		for _, r := range strings.ToLower(c.BinaryName) {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' && b.Len() > 0 {
+				b.WriteRune(r)
+			}
+		}
+		c.PkgPrefix = b.String()
+	}
+	if c.Port == 0 {
+		c.Port = DefaultPort
+	}
+	if c.AdminPort == 0 {
+		c.AdminPort = DefaultAdminPort
+	}
+}
+
+// Validate reports the first field that cannot be rendered safely.
+// Features must already be resolved.
+func (c Config) Validate() error {
+	if !modulePathRE.MatchString(c.Module) {
+		return fmt.Errorf("invalid module path %q", c.Module)
+	}
+	for _, elem := range strings.Split(c.Module, "/") {
+		if elem == "." || elem == ".." || strings.HasSuffix(elem, ".") {
+			return fmt.Errorf("invalid module path %q: bad element %q", c.Module, elem)
+		}
+	}
+	if !binaryNameRE.MatchString(c.BinaryName) {
+		return fmt.Errorf("invalid binary name %q", c.BinaryName)
+	}
+	if strings.TrimSpace(c.AppName) == "" || strings.ContainsAny(c.AppName, "\r\n") {
+		return fmt.Errorf("invalid app name %q", c.AppName)
+	}
+	if !pkgPrefixRE.MatchString(c.PkgPrefix) {
+		return fmt.Errorf("invalid package prefix %q: want a lowercase letter followed by letters or digits", c.PkgPrefix)
+	}
+	for _, p := range []int{c.Port, c.AdminPort} {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port %d", p)
+		}
+	}
+	if c.Port == c.AdminPort {
+		return fmt.Errorf("port and admin port must differ (both %d)", c.Port)
+	}
+	resolved, err := ResolveFeatures(c.Features)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(resolved, c.Features) {
+		return fmt.Errorf("features %v are not resolved (want %v)", c.Features, resolved)
+	}
+	return nil
+}
+
+// templateData is what skeleton templates see.
+type templateData struct {
+	Config
+	GoVersion string
+}
+
+// Has reports whether the project includes the named feature.
+func (d templateData) Has(name string) bool {
+	return slices.Contains(d.Features, name)
+}
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+}
+
+// File is one rendered project file. Path is slash-separated and relative
+// to the project root.
+type File = fsutil.File
+
+// Render renders the skeleton for cfg, sorted by path. Defaults are applied
+// and features resolved on a copy of cfg before validation.
+func Render(cfg Config) ([]File, error) {
+	if err := cfg.resolve(); err != nil {
+		return nil, err
+	}
+	data := templateData{Config: cfg, GoVersion: GoVersion}
+
+	var files []File
+	seen := make(map[string]string)
+	for _, dir := range append([]string{baseDir}, cfg.Features...) {
+		root := skeletonRoot + "/" + dir
+		err := fs.WalkDir(skeletonFS, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			f, err := renderFile(p, strings.TrimPrefix(p, root+"/"), data)
+			if err != nil {
+				return err
+			}
+			if prev, ok := seen[f.Path]; ok {
+				return fmt.Errorf("%s is rendered by both %s and %s", f.Path, prev, dir)
+			}
+			seen[f.Path] = dir
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// resolve applies defaults, resolves features and validates.
+func (c *Config) resolve() error {
+	c.SetDefaults()
+	features, err := ResolveFeatures(c.Features)
+	if err != nil {
+		return err
+	}
+	c.Features = features
+	return c.Validate()
+}
+
+// renderFile renders the skeleton file p whose project path is rel.
+func renderFile(p, rel string, data templateData) (File, error) {
+	src, err := skeletonFS.ReadFile(p)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	rel, err = execute(p, rel, data)
+	if err != nil {
+		return File{}, err
+	}
+	if strings.HasSuffix(rel, templateExt) {
+		rel = strings.TrimSuffix(rel, templateExt)
+		out, err := execute(p, string(src), data)
+		if err != nil {
+			return File{}, err
+		}
+		src = []byte(out)
+		// conditional sections leave alignment to gofmt
+		if strings.HasSuffix(rel, ".go") {
+			if src, err = format.Source(src); err != nil {
+				return File{}, fmt.Errorf("failed to format %s: %w", p, err)
+			}
+		}
+	}
+	return File{Path: rel, Data: src}, nil
+}
+
+func execute(name, text string, data templateData) (string, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Generate renders the skeleton for cfg into dir along with the lock file
+// and base copies that goobtool update needs. It returns the rendered
+// project files.
+func Generate(dir string, cfg Config, force bool) ([]File, error) {
+	files, err := Render(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.resolve(); err != nil {
+		return nil, err
+	}
+	state, err := stateFiles(cfg, TemplateVersion, files)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.Write(dir, append(slices.Clip(files), state...), force); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
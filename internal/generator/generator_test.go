@@ -0,0 +1,430 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/fsutil"
+)
+
+func TestSetDefaults(t *testing.T) {
+	tests := []struct {
+		module string
+		want   Config
+	}{
+		{"github.com/acme/widgets", Config{BinaryName: "widgets", AppName: "widgets", PkgPrefix: "widgets"}},
+		{"github.com/acme/widgets/v2", Config{BinaryName: "widgets", AppName: "widgets", PkgPrefix: "widgets"}},
+		{"example.com/Goob-Tool", Config{BinaryName: "Goob-Tool", AppName: "Goob-Tool", PkgPrefix: "goobtool"}},
+		{"v2", Config{BinaryName: "v2", AppName: "v2", PkgPrefix: "v2"}},
+	}
+	for _, tt := range tests {
+		cfg := Config{Module: tt.module}
+		cfg.SetDefaults()
+		tt.want.Module, tt.want.Port, tt.want.AdminPort = tt.module, DefaultPort, DefaultAdminPort
+		if !reflect.DeepEqual(cfg, tt.want) {
+			t.Errorf("SetDefaults(%q) = %+v, want %+v", tt.module, cfg, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Config{Module: "github.com/acme/widgets"}
+	valid.SetDefaults()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate(%+v) = %v", valid, err)
+	}
+
+	for name, mutate := range map[string]func(*Config){
+		"empty module":  func(c *Config) { c.Module = "" },
+		"module space":  func(c *Config) { c.Module = "github.com/acme/my app" },
+		"module dotdot": func(c *Config) { c.Module = "github.com/../widgets" },
+		"binary slash":  func(c *Config) { c.BinaryName = "a/b" },
+		"prefix upper":  func(c *Config) { c.PkgPrefix = "Goob" },
+		"prefix digit":  func(c *Config) { c.PkgPrefix = "1goob" },
+		"app newline":   func(c *Config) { c.AppName = "a\nb" },
+		"port range":    func(c *Config) { c.Port = 70000 },
+		"same ports":    func(c *Config) { c.AdminPort = c.Port },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: Validate(%+v) succeeded", name, cfg)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	files, err := Render(Config{Module: "github.com/acme/widgets", AppName: `Acme "Widgets"`, Port: 9000, Features: []string{"sessions"}})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	byPath := make(map[string]string)
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, templateExt) {
+			t.Errorf("%s kept its template suffix", f.Path)
+		}
+		byPath[f.Path] = string(f.Data)
+	}
+	for _, p := range []string{"go.mod", "cmd/widgets/main.go", "internal/server/server.go", "internal/admin/admin.go", "internal/session/session.go", "internal/store/store.go", "templates/index.html", "README.md"} {
+		if _, ok := byPath[p]; !ok {
+			t.Errorf("missing %s", p)
+		}
+	}
+
+	if !strings.HasPrefix(byPath["go.mod"], "module github.com/acme/widgets\n") {
+		t.Errorf("go.mod = %q", byPath["go.mod"])
+	}
+	main := byPath["cmd/widgets/main.go"]
+	for _, want := range []string{`"github.com/acme/widgets/internal/server"`, `const appName = "Acme \"Widgets\""`, `flag.Int("port", 9000,`, `"WIDGETS_DB"`} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.go does not contain %s", want)
+		}
+	}
+	// html/template actions in pages are copied, not rendered
+	if !strings.Contains(byPath["templates/index.html"], "{{.AppName}}") {
+		t.Error("templates/index.html lost its template actions")
+	}
+
+	for p, src := range byPath {
+		if !strings.HasSuffix(p, ".go") {
+			continue
+		}
+		formatted, err := format.Source([]byte(src))
+		if err != nil {
+			t.Errorf("%s does not parse: %v", p, err)
+		} else if string(formatted) != src {
+			t.Errorf("%s is not gofmt-formatted", p)
+		}
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	if _, err := Render(Config{}); err == nil {
+		t.Error("Render without a module succeeded")
+	}
+	if _, err := Render(Config{Module: "example.com/shop", Features: []string{"blockchain"}}); err == nil {
+		t.Error("Render with an unknown feature succeeded")
+	}
+}
+
+func TestResolveFeatures(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{nil, []string{}},
+		{[]string{"csrf"}, []string{"sessions", "csrf"}},
+		{[]string{"postgres", "rbac", " CSRF ", "rbac"}, []string{"sessions", "csrf", "rbac", "postgres"}},
+	}
+	for _, tt := range tests {
+		got, err := ResolveFeatures(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolveFeatures(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ResolveFeatures([]string{"sessions", "nope"}); err == nil {
+		t.Error("unknown feature accepted")
+	}
+	for _, f := range Features {
+		for _, dep := range f.Requires {
+			if _, ok := lookupFeature(dep); !ok {
+				t.Errorf("%s requires unknown feature %s", f.Name, dep)
+			}
+		}
+	}
+}
+
+func TestRenderFeatures(t *testing.T) {
+	// every combination must produce parseable Go; spot-check the wiring
+	for mask := range 1 << len(Features) {
+		var with []string
+		for i, f := range Features {
+			if mask&(1<<i) != 0 {
+				with = append(with, f.Name)
+			}
+		}
+		files, err := Render(Config{Module: "example.com/shop", Features: with})
+		if err != nil {
+			t.Fatalf("Render(%v) failed: %v", with, err)
+		}
+		resolved, _ := ResolveFeatures(with)
+		has := func(name string) bool { return slices.Contains(resolved, name) }
+
+		byPath := make(map[string]string)
+		for _, f := range files {
+			byPath[f.Path] = string(f.Data)
+		}
+		main := byPath["cmd/shop/main.go"]
+		checks := map[string]bool{
+			"internal/session/session.go": has("sessions"),
+			"internal/csrf/csrf.go":       has("csrf"),
+			"internal/rbac/rbac.go":       has("rbac"),
+			"internal/store/postgres.go":  has("postgres"),
+		}
+		for p, want := range checks {
+			if _, ok := byPath[p]; ok != want {
+				t.Errorf("with %v: %s present = %v, want %v", resolved, p, ok, want)
+			}
+		}
+		wiring := map[string]bool{
+			"srv.Use(sessions.Middleware)":   has("sessions"),
+			"srv.Use(csrf.Middleware)":       has("csrf"),
+			"rbac.New(st).InitSchema(ctx)":   has("rbac"),
+			"store.OpenPostgres(ctx":         has("postgres"),
+			`"github.com/jackc/pgx/v5`:       false,
+			`ActiveSessions: sessions.Len()`: has("sessions"),
+		}
+		for snippet, want := range wiring {
+			if strings.Contains(main, snippet) != want {
+				t.Errorf("with %v: main.go contains %q = %v, want %v", resolved, snippet, !want, want)
+			}
+		}
+		if strings.Contains(byPath["go.mod"], "github.com/jackc/pgx/v5") != has("postgres") {
+			t.Errorf("with %v: go.mod requires = %q", resolved, byPath["go.mod"])
+		}
+		for p, src := range byPath {
+			if strings.HasSuffix(p, ".go") {
+				if _, err := format.Source([]byte(src)); err != nil {
+					t.Errorf("with %v: %s does not parse: %v", resolved, p, err)
+				}
+			}
+		}
+	}
+}
+
+func readFile(t *testing.T, dir, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestGenerateWritesLock(t *testing.T) {
+	dir := t.TempDir()
+	files, err := Generate(dir, Config{Module: "example.com/shop"}, false)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	lock, err := ReadLock(dir)
+	if err != nil {
+		t.Fatalf("ReadLock failed: %v", err)
+	}
+	if lock.TemplateVersion != TemplateVersion || lock.Config.BinaryName != "shop" || len(lock.Files) != len(files) {
+		t.Errorf("unexpected lock %+v", lock)
+	}
+	for _, f := range files {
+		if lock.Files[f.Path] != hashFile(f.Data) {
+			t.Errorf("lock hash for %s does not match", f.Path)
+		}
+		if readFile(t, dir, BaseDir+"/"+f.Path) != string(f.Data) {
+			t.Errorf("base copy of %s differs", f.Path)
+		}
+	}
+
+	if _, err := Generate(dir, Config{Module: "example.com/shop"}, false); err == nil {
+		t.Error("second Generate without force succeeded")
+	}
+	if _, err := ReadLock(t.TempDir()); !errors.Is(err, ErrNoLock) {
+		t.Errorf("ReadLock of an empty dir = %v, want ErrNoLock", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	v1 := []File{
+		{Path: "edited.go", Data: []byte("package x\n\n// one\n\nfunc A() {}\n")},
+		{Path: "pristine.go", Data: []byte("package x\n")},
+		{Path: "conflict.go", Data: []byte("package x\n\nconst v = 1\n")},
+		{Path: "deleted.go", Data: []byte("package x\n")},
+		{Path: "gone.go", Data: []byte("package x\n")},
+	}
+	cfg := Config{Module: "example.com/shop"}
+	cfg.SetDefaults()
+	state, err := stateFiles(cfg, "0.1.0", v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Write(dir, append(v1, state...), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// local edits
+	local := map[string]string{
+		"edited.go":   "package x\n\n// one\n\nfunc A() {}\n\nfunc Mine() {}\n",
+		"conflict.go": "package x\n\nconst v = 100\n",
+	}
+	for p, data := range local {
+		if err := os.WriteFile(filepath.Join(dir, p), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "deleted.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	v2 := []File{
+		{Path: "edited.go", Data: []byte("package x\n\n// one, revised\n\nfunc A() {}\n")},
+		{Path: "pristine.go", Data: []byte("package x\n\n// new comment\n")},
+		{Path: "conflict.go", Data: []byte("package x\n\nconst v = 2\n")},
+		{Path: "deleted.go", Data: []byte("package x\n\n// changed\n")},
+		{Path: "added.go", Data: []byte("package x\n")},
+	}
+	lock, err := ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := update(dir, lock, v2, "0.2.0", true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if res.Conflicts() != 1 || readFile(t, dir, "pristine.go") != "package x\n" {
+		t.Errorf("dry run: %d conflicts, or files written", res.Conflicts())
+	}
+
+	res, err = update(dir, lock, v2, "0.2.0", false)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	want := map[string]Action{
+		"added.go":    ActionAdded,
+		"conflict.go": ActionConflict,
+		"deleted.go":  ActionSkipped,
+		"edited.go":   ActionMerged,
+		"gone.go":     ActionObsolete,
+		"pristine.go": ActionUpdated,
+	}
+	for _, c := range res.Changes {
+		if want[c.Path] != c.Action {
+			t.Errorf("%s: %s, want %s", c.Path, c.Action, want[c.Path])
+		}
+	}
+	if res.Conflicts() != 1 || len(res.Changes) != len(want) {
+		t.Errorf("changes = %+v", res.Changes)
+	}
+
+	if got := readFile(t, dir, "edited.go"); got != "package x\n\n// one, revised\n\nfunc A() {}\n\nfunc Mine() {}\n" {
+		t.Errorf("edited.go = %q", got)
+	}
+	if got := readFile(t, dir, "conflict.go"); got != "package x\n\n<<<<<<< local\nconst v = 100\n=======\nconst v = 2\n>>>>>>> goobtool 0.2.0\n" {
+		t.Errorf("conflict.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "deleted.go")); !os.IsNotExist(err) {
+		t.Error("locally deleted file came back")
+	}
+	if readFile(t, dir, "gone.go") != "package x\n" {
+		t.Error("obsolete file was touched")
+	}
+
+	// the lock and base copies now describe the new template output
+	lock, err = ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.TemplateVersion != "0.2.0" || len(lock.Files) != len(v2) {
+		t.Errorf("lock after update = %+v", lock)
+	}
+	if readFile(t, dir, BaseDir+"/edited.go") != string(v2[0].Data) {
+		t.Error("base copy not advanced")
+	}
+	if _, err := os.Stat(filepath.Join(dir, BaseDir, "gone.go")); !os.IsNotExist(err) {
+		t.Error("base copy of an obsolete file kept")
+	}
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	if err := checkUpgrade("0.1.0", "0.2.0"); err != nil {
+		t.Errorf("upgrade refused: %v", err)
+	}
+	if err := checkUpgrade("0.2.0", "0.2.0"); err != nil {
+		t.Errorf("same version refused: %v", err)
+	}
+	if err := checkUpgrade("0.3.0", "0.2.0"); err == nil {
+		t.Error("downgrade allowed")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Generate(dir, Config{Module: "example.com/shop"}, false); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// a local edit to the wiring file survives the add
+	mainPath := filepath.Join(dir, "cmd", "shop", "main.go")
+	edited := strings.Replace(readFile(t, dir, "cmd/shop/main.go"), "func envOr(", "// local note\nfunc envOr(", 1)
+	if err := os.WriteFile(mainPath, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Add(dir, []string{"csrf"}, false)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if res.Conflicts() != 0 {
+		t.Fatalf("Add produced conflicts: %+v", res.Changes)
+	}
+	main := readFile(t, dir, "cmd/shop/main.go")
+	for _, want := range []string{"srv.Use(sessions.Middleware)", "srv.Use(csrf.Middleware)", "// local note"} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.go after add lacks %q", want)
+		}
+	}
+	for _, p := range []string{"internal/session/session.go", "internal/csrf/csrf.go"} {
+		readFile(t, dir, p)
+	}
+	lock, err := ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lock.Config.Features, []string{"sessions", "csrf"}) {
+		t.Errorf("lock features = %v", lock.Config.Features)
+	}
+
+	if _, err := Add(dir, []string{"sessions"}, false); err == nil {
+		t.Error("adding a present feature succeeded")
+	}
+	if _, err := Add(dir, []string{"nope"}, false); err == nil {
+		t.Error("adding an unknown feature succeeded")
+	}
+}
+
+func TestReadLockBeforeFeatures(t *testing.T) {
+	dir := t.TempDir()
+	lock := `{"templateVersion": "0.1.0", "config": {"module": "example.com/shop"}, "files": {}}`
+	if err := os.WriteFile(filepath.Join(dir, LockFile), []byte(lock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Config.Features, []string{"sessions"}) {
+		t.Errorf("features = %v, want [sessions]", got.Config.Features)
+	}
+}
+
+func TestReadLockInvalidPath(t *testing.T) {
+	for _, name := range []string{"../outside.go", "/etc/passwd", "a/../../b", "a//b", "."} {
+		dir := t.TempDir()
+		lock, err := json.Marshal(Lock{TemplateVersion: TemplateVersion, Files: map[string]string{name: "sha256:00"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, LockFile), lock, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadLock(dir); err == nil {
+			t.Errorf("ReadLock accepted %q", name)
+		}
+	}
+}
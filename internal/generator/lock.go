@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Files goobtool keeps in a generated project. Both belong in version
+// control: update merges against the base copies.
+const (
+	LockFile = "goobtool.lock"
+	BaseDir  = ".goobtool/base"
+)
+
+// Lock records what was generated: the template version, the fields it was
+// rendered with and a hash of every rendered file as written by the
+// template, before any local edits.
+type Lock struct {
+	TemplateVersion string            `json:"templateVersion"`
+	Config          Config            `json:"config"`
+	Files           map[string]string `json:"files"`
+}
+
+// hashFile returns the lock file hash of data.
+func hashFile(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// stateFiles returns the lock file and base copies for files rendered from
+// cfg at version.
+func stateFiles(cfg Config, version string, files []File) ([]File, error) {
+	lock := Lock{TemplateVersion: version, Config: cfg, Files: make(map[string]string, len(files))}
+	state := make([]File, 0, len(files)+1)
+	for _, f := range files {
+		lock.Files[f.Path] = hashFile(f.Data)
+		state = append(state, File{Path: path.Join(BaseDir, f.Path), Data: f.Data})
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	return append(state, File{Path: LockFile, Data: append(data, '\n')}), nil
+}
+
+// ErrNoLock is returned by ReadLock for directories goobtool did not generate.
+var ErrNoLock = errors.New("no " + LockFile + " found")
+
+// ReadLock reads the lock file of the project in dir.
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoLock
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	if lock.Files == nil {
+		lock.Files = map[string]string{}
+	}
+	// paths are joined onto dir and the base directory by update, so a
+	// tampered lock must not name files outside the project
+	for name := range lock.Files {
+		if !fs.ValidPath(name) || name == "." {
+			return nil, fmt.Errorf("invalid path %q in %s", name, LockFile)
+		}
+	}
+	// before features were optional every project had sessions
+	if lock.Config.Features == nil && lock.TemplateVersion == "0.1.0" {
+		lock.Config.Features = []string{"sessions"}
+	}
+	return &lock, nil
+}
@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Merge3 applies the changes between base and theirs to ours, line by line,
+// the way diff3 and git merge do. Regions changed differently on both sides
+// are written with git-style conflict markers labelled oursLabel and
+// theirsLabel; conflicts is the number of such regions.
+func Merge3(base, ours, theirs []byte, oursLabel, theirsLabel string) (merged []byte, conflicts int) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchA, matchB := lcsMatch(o, a), lcsMatch(o, b)
+
+	var out bytes.Buffer
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// stable run: the same base line kept on both sides
+		for i < len(o) && matchA[i] == j && matchB[i] == k {
+			out.WriteString(o[i])
+			i, j, k = i+1, j+1, k+1
+		}
+		if i == len(o) && j == len(a) && k == len(b) {
+			break
+		}
+
+		// the unstable chunk ends at the next base line kept on both sides
+		ni, nj, nk := i, len(a), len(b)
+		for ; ni < len(o); ni++ {
+			if matchA[ni] >= 0 && matchB[ni] >= 0 {
+				nj, nk = matchA[ni], matchB[ni]
+				break
+			}
+		}
+		co, ca, cb := o[i:ni], a[j:nj], b[k:nk]
+		switch {
+		case slices.Equal(ca, co):
+			writeLines(&out, cb)
+		case slices.Equal(cb, co), slices.Equal(ca, cb):
+			writeLines(&out, ca)
+		default:
+			conflicts++
+			out.WriteString("<<<<<<< " + oursLabel + "\n")
+			writeLines(&out, ca)
+			endLine(&out)
+			out.WriteString("=======\n")
+			writeLines(&out, cb)
+			endLine(&out)
+			out.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+		i, j, k = ni, nj, nk
+	}
+	return out.Bytes(), conflicts
+}
+
+// splitLines splits data after each newline, keeping the newlines.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		n := bytes.IndexByte(data, '\n') + 1
+		if n == 0 {
+			n = len(data)
+		}
+		lines = append(lines, string(data[:n]))
+		data = data[n:]
+	}
+	return lines
+}
+
+func writeLines(buf *bytes.Buffer, lines []string) {
+	for _, l := range lines {
+		buf.WriteString(l)
+	}
+}
+
+// endLine terminates a final line that had no newline so a marker can follow.
+func endLine(buf *bytes.Buffer) {
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
+
+// lcsMatch returns, for each line of x, the index of the line of y it is
+// paired with in a longest common subsequence, or -1.
+func lcsMatch(x, y []string) []int {
+	match := make([]int, len(x))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// common prefix and suffix need no table
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		match[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		match[len(x)-1-suf] = len(y) - 1 - suf
+		suf++
+	}
+	xs, ys := x[pre:len(x)-suf], y[pre:len(y)-suf]
+	n, m := len(xs), len(ys)
+	if n == 0 || m == 0 {
+		return match
+	}
+
+	// lengths[i][j] is the LCS length of xs[i:] and ys[j:]
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if xs[i] == ys[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case xs[i] == ys[j]:
+			match[pre+i] = pre + j
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
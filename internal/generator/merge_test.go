@@ -0,0 +1,42 @@
+package generator
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	const base = "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		{"no changes", base, base, base, 0},
+		{"ours only", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", 0},
+		{"theirs only", base, "a\nb\nc\nD\ne\n", "a\nb\nc\nD\ne\n", 0},
+		{"both, apart", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", 0},
+		{"insert and delete", "a\nb\nnew\nc\nd\ne\n", "a\nb\nc\ne\n", "a\nb\nnew\nc\ne\n", 0},
+		{"appends", "a\nb\nc\nd\ne\nmine\n", "a\nb\nc\nd\ne\ntheirs\n",
+			"a\nb\nc\nd\ne\n<<<<<<< local\nmine\n=======\ntheirs\n>>>>>>> new\n", 1},
+		{"conflict", "a\nours\nc\nd\ne\n", "a\ntheirs\nc\nd\ne\n",
+			"a\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> new\nc\nd\ne\n", 1},
+		{"no final newline", "a\nb\nc\nd\nours", "a\nb\nc\nd\ntheirs",
+			"a\nb\nc\nd\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> new\n", 1},
+	}
+	for _, tt := range tests {
+		got, n := Merge3([]byte(base), []byte(tt.ours), []byte(tt.theirs), "local", "new")
+		if string(got) != tt.want || n != tt.wantConflicts {
+			t.Errorf("%s: Merge3 = %q, %d conflicts; want %q, %d", tt.name, got, n, tt.want, tt.wantConflicts)
+		}
+	}
+}
+
+func TestMerge3NoBase(t *testing.T) {
+	got, n := Merge3(nil, []byte("same\n"), []byte("same\n"), "local", "new")
+	if string(got) != "same\n" || n != 0 {
+		t.Errorf("identical sides: %q, %d", got, n)
+	}
+	if _, n := Merge3(nil, []byte("mine\n"), []byte("theirs\n"), "local", "new"); n != 1 {
+		t.Errorf("different sides without a base: %d conflicts, want 1", n)
+	}
+}
@@ -0,0 +1,219 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/fsutil"
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// Action is what Update did with one file.
+type Action string
+
+const (
+	ActionUnchanged Action = "unchanged" // already matches the new template output
+	ActionAdded     Action = "added"     // new in the template
+	ActionUpdated   Action = "updated"   // not edited locally; replaced
+	ActionMerged    Action = "merged"    // edited locally; template changes merged cleanly
+	ActionConflict  Action = "conflict"  // edited locally; written with conflict markers
+	ActionSkipped   Action = "skipped"   // deleted locally; left deleted
+	ActionObsolete  Action = "obsolete"  // no longer in the template; left in place
+)
+
+// Change is one file's outcome.
+type Change struct {
+	Path      string
+	Action    Action
+	Conflicts int
+}
+
+// UpdateResult summarizes an update.
+type UpdateResult struct {
+	From, To string
+	Changes  []Change
+}
+
+// Conflicts returns the number of files written with conflict markers.
+func (r *UpdateResult) Conflicts() int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == ActionConflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Update re-renders the project in dir with the current template and the
+// fields recorded in its lock file. Files not edited since they were
+// generated are replaced; edited files get a three-way merge of the
+// template's changes, with git-style markers where both sides changed the
+// same lines. Unless dryRun is set, the lock file and base copies are then
+// advanced to the new template output.
+func Update(dir string, dryRun bool) (*UpdateResult, error) {
+	lock, err := ReadLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpgrade(lock.TemplateVersion, TemplateVersion); err != nil {
+		return nil, err
+	}
+	files, err := Render(lock.Config)
+	if err != nil {
+		return nil, err
+	}
+	return update(dir, lock, files, TemplateVersion, dryRun)
+}
+
+// Add adds features, with their dependencies, to the project in dir: their
+// files are rendered and the base files that wire them in are updated the
+// same way Update does it. The project must already be at the current
+// template version.
+func Add(dir string, features []string, dryRun bool) (*UpdateResult, error) {
+	lock, err := ReadLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	if lock.TemplateVersion != TemplateVersion {
+		return nil, fmt.Errorf("project is at template %s, this goobtool renders %s; run goobtool update first", lock.TemplateVersion, TemplateVersion)
+	}
+
+	cfg := lock.Config
+	cfg.Features = append(slices.Clone(cfg.Features), features...)
+	if err := cfg.resolve(); err != nil {
+		return nil, err
+	}
+	if slices.Equal(cfg.Features, lock.Config.Features) {
+		return nil, fmt.Errorf("project already has %s", strings.Join(features, ", "))
+	}
+	files, err := Render(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lock.Config = cfg
+	return update(dir, lock, files, TemplateVersion, dryRun)
+}
+
+// checkUpgrade refuses to take a project back to an older template.
+func checkUpgrade(from, to string) error {
+	fv, err := store.ParseVersion(from)
+	if err != nil {
+		return fmt.Errorf("invalid template version in %s: %w", LockFile, err)
+	}
+	tv, err := store.ParseVersion(to)
+	if err != nil {
+		return err
+	}
+	if tv.Less(fv) {
+		return fmt.Errorf("project was generated with template %s, newer than this goobtool's %s", from, to)
+	}
+	return nil
+}
+
+func update(dir string, lock *Lock, files []File, version string, dryRun bool) (*UpdateResult, error) {
+	res := &UpdateResult{From: lock.TemplateVersion, To: version}
+	theirsLabel := "goobtool " + version
+
+	var writes []File
+	rendered := make(map[string]bool, len(files))
+	for _, f := range files {
+		rendered[f.Path] = true
+		lockedHash, generated := lock.Files[f.Path]
+
+		current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && generated:
+			res.Changes = append(res.Changes, Change{Path: f.Path, Action: ActionSkipped})
+			continue
+		case errors.Is(err, fs.ErrNotExist):
+			res.Changes = append(res.Changes, Change{Path: f.Path, Action: ActionAdded})
+			writes = append(writes, f)
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+
+		if hashFile(current) == hashFile(f.Data) {
+			res.Changes = append(res.Changes, Change{Path: f.Path, Action: ActionUnchanged})
+			continue
+		}
+		if generated && hashFile(current) == lockedHash {
+			res.Changes = append(res.Changes, Change{Path: f.Path, Action: ActionUpdated})
+			writes = append(writes, f)
+			continue
+		}
+
+		// edited locally, or created by hand where the template now has a file
+		base, err := readBase(dir, f.Path, lockedHash)
+		if err != nil {
+			return nil, err
+		}
+		merged, conflicts := Merge3(base, current, f.Data, "local", theirsLabel)
+		if conflicts == 0 && string(merged) == string(current) {
+			// the template did not change what was edited
+			res.Changes = append(res.Changes, Change{Path: f.Path, Action: ActionUnchanged})
+			continue
+		}
+		action := ActionMerged
+		if conflicts > 0 {
+			action = ActionConflict
+		}
+		res.Changes = append(res.Changes, Change{Path: f.Path, Action: action, Conflicts: conflicts})
+		writes = append(writes, File{Path: f.Path, Data: merged})
+	}
+	for p := range lock.Files {
+		if !rendered[p] {
+			res.Changes = append(res.Changes, Change{Path: p, Action: ActionObsolete})
+		}
+	}
+	sort.Slice(res.Changes, func(i, j int) bool { return res.Changes[i].Path < res.Changes[j].Path })
+
+	if dryRun {
+		return res, nil
+	}
+
+	// obsolete files drop out of the lock; their base copies go with them
+	for p := range lock.Files {
+		if !rendered[p] {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(path.Join(BaseDir, p)))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove base copy of %s: %w", p, err)
+			}
+		}
+	}
+	state, err := stateFiles(lock.Config, version, files)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.Write(dir, append(writes, state...), true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readBase returns the base copy of p if it still matches the lock file.
+// Without one every local line is treated as an addition, so changed
+// regions surface as conflicts rather than being overwritten.
+func readBase(dir, p, lockedHash string) ([]byte, error) {
+	if lockedHash == "" {
+		return nil, nil
+	}
+	base, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path.Join(BaseDir, p))))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base copy of %s: %w", p, err)
+	}
+	if hashFile(base) != lockedHash {
+		return nil, nil
+	}
+	return base, nil
+}
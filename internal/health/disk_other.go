@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package health
+
+// diskFree is not implemented on this platform; the disk probe is skipped.
+func diskFree(dir string) (uint64, error) {
+	return 0, ErrSkipped
+}
@@ -0,0 +1,228 @@
+// Package health runs readiness probes registered by components.
+//
+// A Registry aggregates probes into a Report. The public readiness handler
+// exposes only READY/NOT_READY; the JSON detail handler with per-probe
+// results is meant for the admin listener.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single probe when the registry has no timeout set.
+const DefaultTimeout = 2 * time.Second
+
+// Probe status values.
+const (
+	StatusOK      = "ok"
+	StatusFail    = "fail"
+	StatusSkipped = "skipped"
+)
+
+// ErrSkipped is returned by a probe that cannot run on this platform or in
+// the current configuration. Skipped probes do not fail readiness.
+var ErrSkipped = errors.New("probe skipped")
+
+// Probe checks one dependency. Check must honor ctx cancellation.
+type Probe func(ctx context.Context) error
+
+// Result is the outcome of a single probe.
+type Result struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"durationMs"`
+}
+
+// Report aggregates probe results. Ready is false if any probe failed.
+type Report struct {
+	Ready   bool      `json:"ready"`
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"checks"`
+}
+
+type namedProbe struct {
+	name  string
+	probe Probe
+}
+
+// Registry holds probes in registration order. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	probes  []namedProbe
+
+	// cached report for the public readiness handler
+	cacheTTL time.Duration
+	last     *Report
+	inflight *probeRun
+}
+
+// probeRun is a probe run shared by concurrent cachedCheck callers.
+type probeRun struct {
+	done chan struct{}
+	rep  Report
+}
+
+// NewRegistry returns a registry that gives each probe up to timeout.
+// Zero means DefaultTimeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Registry{timeout: timeout, cacheTTL: time.Second}
+}
+
+// Register adds a probe. Registering a name twice replaces the earlier probe.
+func (r *Registry) Register(name string, p Probe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.probes {
+		if r.probes[i].name == name {
+			r.probes[i].probe = p
+			r.last, r.inflight = nil, nil
+			return
+		}
+	}
+	r.probes = append(r.probes, namedProbe{name: name, probe: p})
+	r.last, r.inflight = nil, nil
+}
+
+// Check runs all probes concurrently and returns the aggregated report.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	probes := append([]namedProbe(nil), r.probes...)
+	timeout := r.timeout
+	r.mu.Unlock()
+
+	rep := Report{Ready: true, Time: time.Now().UTC(), Results: make([]Result, len(probes))}
+	var wg sync.WaitGroup
+	for i, np := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rep.Results[i] = run(ctx, np, timeout)
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range rep.Results {
+		if res.Status == StatusFail {
+			rep.Ready = false
+		}
+	}
+	return rep
+}
+
+// cachedCheck returns a report no older than the cache TTL, so that public
+// /ready traffic cannot drive probe load. Concurrent callers share one probe
+// run, which is detached from the caller's cancellation and bounded by the
+// registry timeout. A caller whose ctx ends first gets a not-ready report;
+// the run still completes and is cached for the next request.
+func (r *Registry) cachedCheck(ctx context.Context) Report {
+	r.mu.Lock()
+	if r.last != nil && time.Since(r.last.Time) < r.cacheTTL {
+		rep := *r.last
+		r.mu.Unlock()
+		return rep
+	}
+	f := r.inflight
+	if f == nil {
+		f = &probeRun{done: make(chan struct{})}
+		r.inflight = f
+		go r.refresh(context.WithoutCancel(ctx), f)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.rep
+	case <-ctx.Done():
+		return Report{Time: time.Now().UTC()}
+	}
+}
+
+// refresh runs the probes for cachedCheck and caches the report.
+func (r *Registry) refresh(ctx context.Context, f *probeRun) {
+	f.rep = r.Check(ctx)
+	r.mu.Lock()
+	if r.inflight == f {
+		r.last = &f.rep
+		r.inflight = nil
+	}
+	r.mu.Unlock()
+	close(f.done)
+}
+
+func run(ctx context.Context, np namedProbe, timeout time.Duration) (res Result) {
+	res.Name = np.name
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		res.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	// run in a goroutine so a probe that ignores ctx cannot hold the report
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- errors.New("probe panicked")
+			}
+		}()
+		done <- np.probe(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	switch {
+	case err == nil:
+		res.Status = StatusOK
+	case errors.Is(err, ErrSkipped):
+		res.Status, res.Error = StatusSkipped, err.Error()
+	default:
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	return res
+}
+
+// ReadyHandler serves the public readiness check: 200 READY or
+// 503 NOT_READY, with no probe details.
+func (r *Registry) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if !r.cachedCheck(req.Context()).Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("NOT_READY"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("READY"))
+	})
+}
+
+// DetailHandler serves the full report as JSON, with 503 when not ready.
+// Mount it on the admin listener only.
+func (r *Registry) DetailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rep := r.Check(req.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !rep.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
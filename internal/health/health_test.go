@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckAggregates(t *testing.T) {
+	reg := NewRegistry(time.Second)
+	reg.Register("ok", func(ctx context.Context) error { return nil })
+	reg.Register("skipped", func(ctx context.Context) error { return ErrSkipped })
+
+	rep := reg.Check(context.Background())
+	if !rep.Ready {
+		t.Fatalf("expected ready, got %+v", rep)
+	}
+	if rep.Results[0].Name != "ok" || rep.Results[1].Status != StatusSkipped {
+		t.Errorf("unexpected results %+v", rep.Results)
+	}
+
+	reg.Register("broken", func(ctx context.Context) error { return errors.New("boom") })
+	rep = reg.Check(context.Background())
+	if rep.Ready {
+		t.Fatal("expected not ready with a failing probe")
+	}
+	if got := rep.Results[2]; got.Status != StatusFail || got.Error != "boom" {
+		t.Errorf("unexpected result %+v", got)
+	}
+}
+
+func TestCheckTimeoutAndPanic(t *testing.T) {
+	reg := NewRegistry(20 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	reg.Register("stuck", func(ctx context.Context) error { <-block; return nil })
+	reg.Register("panics", func(ctx context.Context) error { panic("oops") })
+
+	start := time.Now()
+	rep := reg.Check(context.Background())
+	if time.Since(start) > time.Second {
+		t.Fatal("stuck probe held the report")
+	}
+	for _, res := range rep.Results {
+		if res.Status != StatusFail {
+			t.Errorf("%s: expected fail, got %+v", res.Name, res)
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	reg := NewRegistry(time.Second)
+	reg.Register("db", func(ctx context.Context) error { return errors.New("secret detail") })
+
+	rec := httptest.NewRecorder()
+	reg.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "NOT_READY" {
+		t.Errorf("ready: got %d %q", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Error("public readiness leaked probe details")
+	}
+
+	rec = httptest.NewRecorder()
+	reg.DetailHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/health", nil))
+	var rep Report
+	if err := json.NewDecoder(rec.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || rep.Ready || rep.Results[0].Error != "secret detail" {
+		t.Errorf("detail: got %d %+v", rec.Code, rep)
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	reg := NewRegistry(time.Second)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	reg.Register("slow", func(ctx context.Context) error {
+		calls.Add(1)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	// a caller that gives up does not cancel or cache the shared run
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if reg.cachedCheck(ctx).Ready {
+		t.Error("cancelled caller got a ready report")
+	}
+
+	var wg sync.WaitGroup
+	reports := make([]Report, 8)
+	for i := range reports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[i] = reg.cachedCheck(context.Background())
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("probe ran %d times, want 1", n)
+	}
+	for _, rep := range reports {
+		if !rep.Ready {
+			t.Errorf("shared run not ready: %+v", rep)
+		}
+	}
+	if !reg.cachedCheck(context.Background()).Ready || calls.Load() != 1 {
+		t.Errorf("cached report not reused, probe ran %d times", calls.Load())
+	}
+}
+
+func TestWALSizeProbe(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "goob.db")
+	if err := WALSizeProbe(db, 10)(context.Background()); err != nil {
+		t.Errorf("missing WAL should be healthy: %v", err)
+	}
+	if err := os.WriteFile(db+"-wal", make([]byte, 20), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WALSizeProbe(db, 10)(context.Background()); err == nil {
+		t.Error("expected oversized WAL to fail")
+	}
+}
+
+func TestDiskSpaceProbe(t *testing.T) {
+	dir := t.TempDir()
+	err := DiskSpaceProbe(dir, 1)(context.Background())
+	if errors.Is(err, ErrSkipped) {
+		t.Skip("disk probe unsupported on this platform")
+	}
+	if err != nil {
+		t.Errorf("expected free space in %s: %v", dir, err)
+	}
+	if err := DiskSpaceProbe(dir, 1<<62)(context.Background()); err == nil {
+		t.Error("expected failure for an impossible minimum")
+	}
+}
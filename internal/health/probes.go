@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Pinger is satisfied by *sql.DB and by stores that can check connectivity.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingProbe checks that p answers a ping within the probe timeout.
+// Use it for the main database and for any separate session store.
+func PingProbe(p Pinger) Probe {
+	return func(ctx context.Context) error {
+		if p == nil {
+			return errors.New("not configured")
+		}
+		return p.PingContext(ctx)
+	}
+}
+
+// DiskSpaceProbe fails when the filesystem holding dir has fewer than
+// minFree bytes available to unprivileged users.
+func DiskSpaceProbe(dir string, minFree uint64) Probe {
+	return func(ctx context.Context) error {
+		free, err := diskFree(dir)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free in %s, want at least %d", free, dir, minFree)
+		}
+		return nil
+	}
+}
+
+// WALSizeProbe fails when the SQLite write-ahead log for dbPath has grown
+// beyond maxBytes, which usually means checkpoints are not keeping up.
+// A missing WAL file is healthy.
+func WALSizeProbe(dbPath string, maxBytes int64) Probe {
+	return func(ctx context.Context) error {
+		info, err := os.Stat(dbPath + "-wal")
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat WAL: %w", err)
+		}
+		if info.Size() > maxBytes {
+			return fmt.Errorf("WAL is %d bytes, limit %d", info.Size(), maxBytes)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+)
+
+// ContextLogger is a Logger that can bind request-scoped fields carried
+// by a context (see ContextWith), such as the HTTP request ID.
+type ContextLogger interface {
+	Logger
+	// WithContext returns a Logger that adds the context's fields to every line.
+	WithContext(ctx context.Context) Logger
+}
+
+type fieldsKey struct{}
+
+// ContextWith returns a copy of ctx carrying additional key/value log fields.
+// Fields accumulate, so middleware layers can each contribute their own.
+func ContextWith(ctx context.Context, args ...any) context.Context {
+	prev := ContextFields(ctx)
+	fields := make([]any, 0, len(prev)+len(args))
+	fields = append(fields, prev...)
+	fields = append(fields, args...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// ContextFields returns the key/value log fields stored in ctx.
+func ContextFields(ctx context.Context) []any {
+	fields, _ := ctx.Value(fieldsKey{}).([]any)
+	return fields
+}
+
+// FromContext returns l bound to the log fields carried by ctx.
+// Loggers implementing ContextLogger decide how to bind them; any other
+// Logger gets them attached with With.
+func FromContext(ctx context.Context, l Logger) Logger {
+	if cl, ok := l.(ContextLogger); ok {
+		return cl.WithContext(ctx)
+	}
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return With(l, fields...)
+}
+
+// WithContext implements ContextLogger.
+func (l *SlogLogger) WithContext(ctx context.Context) Logger {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
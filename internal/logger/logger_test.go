@@ -2,7 +2,10 @@ package logger
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"log"
+	"log/slog"
 	"strings"
 	"testing"
 )
@@ -64,3 +67,90 @@ func TestDefault(t *testing.T) {
 
 	Default.Info("test")
 }
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(&buf, FormatJSON, slog.LevelInfo)
+
+	l.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("debug line written below minimum level: %q", buf.String())
+	}
+
+	With(l, "path", "/tmp/x.db", "schema", "0.1").Info("datastore ready count=%d", 3)
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", buf.String(), err)
+	}
+	if line["level"] != "INFO" || line["msg"] != "datastore ready count=3" || line["path"] != "/tmp/x.db" || line["schema"] != "0.1" {
+		t.Errorf("unexpected line: %v", line)
+	}
+
+	buf.Reset()
+	l.SetLevel(slog.LevelDebug)
+	l.Debug("visible")
+	if !strings.Contains(buf.String(), `"msg":"visible"`) {
+		t.Errorf("debug line not written after SetLevel: %q", buf.String())
+	}
+}
+
+func TestSlogLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(&buf, FormatText, slog.LevelInfo)
+	With(l, "port", 8080).Warn("listening")
+	got := buf.String()
+	if !strings.Contains(got, "level=WARN") || !strings.Contains(got, "msg=listening") || !strings.Contains(got, "port=8080") {
+		t.Errorf("unexpected text line: %q", got)
+	}
+}
+
+func TestWithStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := &StdLogger{
+		logger: log.New(&buf, "", 0),
+	}
+	With(With(l, "path", "a.db"), "ratio", "50%").Info("datastore ready")
+	got := strings.TrimSpace(buf.String())
+	want := "[INFO] datastore ready path=a.db ratio=50%"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := ParseFormat("JSON"); err != nil {
+		t.Errorf("ParseFormat(JSON): %v", err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml): expected error")
+	}
+	if level, err := ParseLevel("warn"); err != nil || level != slog.LevelWarn {
+		t.Errorf("ParseLevel(warn) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud): expected error")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := ContextWith(context.Background(), "request_id", "abc")
+	ctx = ContextWith(ctx, "user", "alice")
+
+	var buf bytes.Buffer
+	sl := NewSlogLogger(&buf, FormatText, slog.LevelInfo)
+	FromContext(ctx, sl).Info("handled")
+	if got := buf.String(); !strings.Contains(got, "request_id=abc user=alice") {
+		t.Errorf("slog line missing context fields: %q", got)
+	}
+
+	buf.Reset()
+	std := &StdLogger{logger: log.New(&buf, "", 0)}
+	FromContext(ctx, std).Info("handled")
+	if got, want := strings.TrimSpace(buf.String()), "[INFO] handled request_id=abc user=alice"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if l := FromContext(context.Background(), std); l != Logger(std) {
+		t.Error("FromContext without fields should return the logger unchanged")
+	}
+}
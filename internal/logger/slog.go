@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Format selects the output encoding of a SlogLogger.
+type Format string
+
+const (
+	FormatText Format = "text" // key=value pairs (logfmt style)
+	FormatJSON Format = "json" // one JSON object per line
+)
+
+// ParseFormat converts a --log-format value into a Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q (want text or json)", s)
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) into a slog.Level.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// FieldLogger is a Logger that can carry structured key/value fields.
+type FieldLogger interface {
+	Logger
+	// With returns a Logger that adds the key/value pairs to every line.
+	With(args ...any) Logger
+}
+
+// Leveler is implemented by loggers whose minimum level can change at runtime.
+type Leveler interface {
+	Level() slog.Level
+	SetLevel(level slog.Level)
+}
+
+// SlogLogger implements the Goob logging contract on top of log/slog.
+// Messages keep the printf-style contract; structured fields are attached
+// with With. The minimum level is shared by all loggers derived via With.
+type SlogLogger struct {
+	logger *slog.Logger
+	level  *slog.LevelVar
+}
+
+// NewSlogLogger creates a SlogLogger writing to w in the given format.
+// Lines below level are discarded.
+func NewSlogLogger(w io.Writer, format Format, level slog.Level) *SlogLogger {
+	lv := new(slog.LevelVar)
+	lv.Set(level)
+	opts := &slog.HandlerOptions{Level: lv}
+	var h slog.Handler
+	if format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return &SlogLogger{logger: slog.New(h), level: lv}
+}
+
+func (l *SlogLogger) Info(msg string, args ...any) {
+	l.log(slog.LevelInfo, msg, args...)
+}
+
+func (l *SlogLogger) Warn(msg string, args ...any) {
+	l.log(slog.LevelWarn, msg, args...)
+}
+
+func (l *SlogLogger) Error(msg string, args ...any) {
+	l.log(slog.LevelError, msg, args...)
+}
+
+func (l *SlogLogger) Debug(msg string, args ...any) {
+	l.log(slog.LevelDebug, msg, args...)
+}
+
+// With implements FieldLogger.
+func (l *SlogLogger) With(args ...any) Logger {
+	return &SlogLogger{logger: l.logger.With(args...), level: l.level}
+}
+
+// Level implements Leveler.
+func (l *SlogLogger) Level() slog.Level {
+	return l.level.Level()
+}
+
+// SetLevel implements Leveler.
+func (l *SlogLogger) SetLevel(level slog.Level) {
+	l.level.Set(level)
+}
+
+// Slog returns the underlying slog.Logger.
+func (l *SlogLogger) Slog() *slog.Logger {
+	return l.logger
+}
+
+func (l *SlogLogger) log(level slog.Level, msg string, args ...any) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	l.logger.Log(ctx, level, msg)
+}
+
+// With returns a Logger that attaches the key/value pairs to every line.
+// Loggers implementing FieldLogger keep the fields structured; any other
+// Logger gets them appended to the message as key=value text.
+func With(l Logger, args ...any) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.With(args...)
+	}
+	return &textFieldLogger{next: l, suffix: formatFields(args)}
+}
+
+// textFieldLogger adds fields to a Logger without structured support.
+type textFieldLogger struct {
+	next   Logger
+	suffix string
+}
+
+func (l *textFieldLogger) Info(msg string, args ...any) {
+	l.next.Info(l.format(msg, args))
+}
+
+func (l *textFieldLogger) Warn(msg string, args ...any) {
+	l.next.Warn(l.format(msg, args))
+}
+
+func (l *textFieldLogger) Error(msg string, args ...any) {
+	l.next.Error(l.format(msg, args))
+}
+
+func (l *textFieldLogger) Debug(msg string, args ...any) {
+	l.next.Debug(l.format(msg, args))
+}
+
+func (l *textFieldLogger) With(args ...any) Logger {
+	return &textFieldLogger{next: l.next, suffix: l.suffix + formatFields(args)}
+}
+
+// format renders the message and escapes it so the wrapped printf-style
+// logger prints it verbatim.
+func (l *textFieldLogger) format(msg string, args []any) string {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return strings.ReplaceAll(msg+l.suffix, "%", "%%")
+}
+
+// formatFields renders key/value pairs as " k1=v1 k2=v2".
+func formatFields(args []any) string {
+	var sb strings.Builder
+	for _, attr := range slog.Group("", args...).Value.Group() {
+		fmt.Fprintf(&sb, " %s=%v", attr.Key, attr.Value)
+	}
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// RuntimeCollector reports Go runtime statistics.
+func RuntimeCollector() Collector {
+	return func(w *Writer) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		w.Gauge("go_info", "Information about the Go environment.", 1, "version", runtime.Version())
+		w.Gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+		w.Gauge("go_sched_gomaxprocs_threads", "The current runtime.GOMAXPROCS setting.", float64(runtime.GOMAXPROCS(0)))
+		w.Gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc))
+		w.Counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(ms.TotalAlloc))
+		w.Gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(ms.Sys))
+		w.Gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse))
+		w.Gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects))
+		w.Counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC))
+		w.Counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time.", time.Duration(ms.PauseTotalNs).Seconds())
+	}
+}
+
+// DBStatsCollector reports connection pool statistics from sql.DB.Stats,
+// labelled by pool name so several pools can be reported side by side.
+func DBStatsCollector(pools map[string]*sql.DB) Collector {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := []struct {
+		name, help string
+		counter    bool
+		value      func(sql.DBStats) float64
+	}{
+		{"goob_db_max_open_connections", "Maximum number of open connections to the database.", false, func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"goob_db_open_connections", "Number of established connections, in use and idle.", false, func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"goob_db_in_use_connections", "Number of connections currently in use.", false, func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"goob_db_idle_connections", "Number of idle connections.", false, func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"goob_db_wait_count_total", "Total number of connections waited for.", true, func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"goob_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", true, func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"goob_db_max_idle_closed_total", "Total connections closed due to SetMaxIdleConns.", true, func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"goob_db_max_idle_time_closed_total", "Total connections closed due to SetConnMaxIdleTime.", true, func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"goob_db_max_lifetime_closed_total", "Total connections closed due to SetConnMaxLifetime.", true, func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	return func(w *Writer) {
+		stats := make([]sql.DBStats, len(names))
+		for i, name := range names {
+			stats[i] = pools[name].Stats()
+		}
+		for _, m := range metrics {
+			for i, name := range names {
+				if m.counter {
+					w.Counter(m.name, m.help, m.value(stats[i]), "pool", name)
+				} else {
+					w.Gauge(m.name, m.help, m.value(stats[i]), "pool", name)
+				}
+			}
+		}
+	}
+}
+
+// UptimeCollector reports process start time and uptime relative to start.
+func UptimeCollector(start time.Time) Collector {
+	return func(w *Writer) {
+		w.Gauge("goob_start_time_seconds", "Start time of the server since unix epoch in seconds.", float64(start.UnixNano())/1e9)
+		w.Gauge("goob_uptime_seconds", "Seconds since the server started.", time.Since(start).Seconds())
+	}
+}
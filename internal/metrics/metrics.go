@@ -0,0 +1,262 @@
+// Package metrics exposes application metrics in the Prometheus text
+// exposition format (version 0.0.4) without external dependencies.
+//
+// A Registry counts HTTP requests and their latency per listener and route,
+// and calls registered Collectors at scrape time for point-in-time values
+// such as runtime and connection pool statistics.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/recorder"
+)
+
+// ContentType is the Prometheus text format media type.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the latency histogram upper bounds in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector writes metrics gathered at scrape time.
+type Collector func(w *Writer)
+
+// Registry holds request metrics and scrape-time collectors.
+// It is safe for concurrent use.
+type Registry struct {
+	mu         sync.Mutex
+	buckets    []float64
+	requests   map[requestKey]uint64
+	latency    map[routeKey]*histogram
+	collectors []Collector
+}
+
+type routeKey struct {
+	listener string
+	route    string
+}
+
+type requestKey struct {
+	routeKey
+	method string
+	code   int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewRegistry creates an empty Registry using DefaultBuckets.
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:  DefaultBuckets,
+		requests: make(map[requestKey]uint64),
+		latency:  make(map[routeKey]*histogram),
+	}
+}
+
+// Register adds collectors that run on every scrape, in order.
+func (reg *Registry) Register(c ...Collector) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.collectors = append(reg.collectors, c...)
+}
+
+// Observe records one completed request.
+func (reg *Registry) Observe(listener, route, method string, code int, d time.Duration) {
+	rk := routeKey{listener: listener, route: route}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.requests[requestKey{routeKey: rk, method: method, code: code}]++
+	h, ok := reg.latency[rk]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(reg.buckets))}
+		reg.latency[rk] = h
+	}
+	secs := d.Seconds()
+	for i, le := range reg.buckets {
+		if secs <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// Middleware records requests served by mux on the named listener.
+// Routes are labelled with the matched ServeMux pattern (not the raw path)
+// to keep label cardinality bounded; unmatched requests use "unmatched".
+func (reg *Registry) Middleware(listener string, mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := recorder.Wrap(w)
+			next.ServeHTTP(rec, r)
+
+			route := "unmatched"
+			if _, pattern := mux.Handler(r); pattern != "" {
+				route = pattern
+			}
+			reg.Observe(listener, route, r.Method, rec.Status(), time.Since(start))
+		})
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (reg *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = reg.WriteTo(w)
+	})
+}
+
+// WriteTo renders all metrics to out.
+func (reg *Registry) WriteTo(out io.Writer) (int64, error) {
+	mw := &Writer{w: out}
+
+	reg.mu.Lock()
+	reg.writeRequests(mw)
+	collectors := append([]Collector(nil), reg.collectors...)
+	reg.mu.Unlock()
+
+	for _, c := range collectors {
+		c(mw)
+	}
+	return mw.n, mw.err
+}
+
+// writeRequests renders the request counter and latency histogram.
+// The caller holds reg.mu.
+func (reg *Registry) writeRequests(mw *Writer) {
+	reqKeys := make([]requestKey, 0, len(reg.requests))
+	for k := range reg.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.listener != b.listener {
+			return a.listener < b.listener
+		}
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	for _, k := range reqKeys {
+		mw.Counter("goob_http_requests_total", "HTTP requests served, by listener, route, method and status code.",
+			float64(reg.requests[k]), "listener", k.listener, "route", k.route, "method", k.method, "code", strconv.Itoa(k.code))
+	}
+
+	routeKeys := make([]routeKey, 0, len(reg.latency))
+	for k := range reg.latency {
+		routeKeys = append(routeKeys, k)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		if routeKeys[i].listener != routeKeys[j].listener {
+			return routeKeys[i].listener < routeKeys[j].listener
+		}
+		return routeKeys[i].route < routeKeys[j].route
+	})
+	const name = "goob_http_request_duration_seconds"
+	for _, k := range routeKeys {
+		h := reg.latency[k]
+		mw.family(name, "HTTP request latency, by listener and route.", "histogram")
+		var cumulative uint64
+		for i, le := range reg.buckets {
+			cumulative += h.counts[i]
+			mw.sample(name+"_bucket", float64(cumulative), "listener", k.listener, "route", k.route, "le", formatFloat(le))
+		}
+		mw.sample(name+"_bucket", float64(h.count), "listener", k.listener, "route", k.route, "le", "+Inf")
+		mw.sample(name+"_sum", h.sum, "listener", k.listener, "route", k.route)
+		mw.sample(name+"_count", float64(h.count), "listener", k.listener, "route", k.route)
+	}
+}
+
+// Writer renders metric families in the text format. HELP and TYPE lines
+// are emitted once for consecutive samples of the same metric.
+type Writer struct {
+	w    io.Writer
+	last string
+	n    int64
+	err  error
+}
+
+// Gauge writes one gauge sample. labels are name/value pairs.
+func (mw *Writer) Gauge(name, help string, value float64, labels ...string) {
+	mw.family(name, help, "gauge")
+	mw.sample(name, value, labels...)
+}
+
+// Counter writes one counter sample. labels are name/value pairs.
+func (mw *Writer) Counter(name, help string, value float64, labels ...string) {
+	mw.family(name, help, "counter")
+	mw.sample(name, value, labels...)
+}
+
+func (mw *Writer) family(name, help, typ string) {
+	if mw.last == name {
+		return
+	}
+	mw.last = name
+	mw.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+func (mw *Writer) sample(name string, value float64, labels ...string) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		sb.WriteByte('}')
+	}
+	mw.printf("%s %s\n", sb.String(), formatFloat(value))
+}
+
+func (mw *Writer) printf(format string, args ...any) {
+	if mw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(mw.w, format, args...)
+	mw.n += int64(n)
+	mw.err = err
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	h := reg.Middleware("public", mux)(mux)
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var sb strings.Builder
+	if _, err := reg.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		`goob_http_requests_total{listener="public",route="/items/{id}",method="GET",code="202"} 2`,
+		`goob_http_requests_total{listener="public",route="unmatched",method="GET",code="404"} 1`,
+		`goob_http_request_duration_seconds_bucket{listener="public",route="/items/{id}",le="+Inf"} 2`,
+		`goob_http_request_duration_seconds_count{listener="public",route="/items/{id}"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "# TYPE goob_http_request_duration_seconds histogram"); n != 1 {
+		t.Errorf("histogram TYPE line written %d times, want 1", n)
+	}
+}
+
+func TestObserveBuckets(t *testing.T) {
+	reg := NewRegistry()
+	reg.Observe("admin", "/admin/status", http.MethodGet, 200, 30*time.Millisecond)
+	reg.Observe("admin", "/admin/status", http.MethodGet, 200, 3*time.Second)
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+	out := sb.String()
+	for _, want := range []string{
+		`le="0.025"} 0`,
+		`le="0.05"} 1`,
+		`le="2.5"} 1`,
+		`le="5"} 2`,
+		`le="+Inf"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestCollectors(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(
+		func(w *Writer) { w.Gauge("goob_test", "Label \"escaping\".", 1.5, "v", "a\"b\\c") },
+		RuntimeCollector(),
+		DBStatsCollector(map[string]*sql.DB{"write": {}, "read": {}}),
+	)
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+	out := sb.String()
+	for _, want := range []string{
+		`goob_test{v="a\"b\\c"} 1.5`,
+		"# TYPE go_goroutines gauge",
+		`goob_db_open_connections{pool="read"} 0`,
+		`goob_db_open_connections{pool="write"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if n := strings.Count(out, "# HELP goob_db_open_connections"); n != 1 {
+		t.Errorf("pool family HELP written %d times, want 1", n)
+	}
+}
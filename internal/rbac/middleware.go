@@ -0,0 +1,111 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+type subjectKey struct{}
+
+// WithSubject returns a copy of ctx carrying the authenticated user ID.
+// Session middleware calls this once it has resolved the request's user.
+func WithSubject(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, userID)
+}
+
+// SubjectFromContext returns the user ID stored by WithSubject, if any.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(subjectKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// Guard enforces role and permission requirements for one route group.
+type Guard struct {
+	store Store
+	log   logger.Logger
+}
+
+// NewGuard creates a Guard that looks up grants in store and reports
+// failures as JSON: { "error": "code", "message": "text" }.
+func NewGuard(store Store, log logger.Logger) *Guard {
+	if log == nil {
+		log = logger.Default
+	}
+	return &Guard{store: store, log: log}
+}
+
+// RequireRole returns middleware that only admits subjects holding role.
+func (g *Guard) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := SubjectFromContext(r.Context())
+			if !ok {
+				g.deny(w, http.StatusUnauthorized, "unauthorized", "authentication required")
+				return
+			}
+			roles, err := g.store.RolesFor(userID)
+			if err != nil {
+				logger.FromContext(r.Context(), g.log).Error("rbac: failed to load roles user=%s: %v", userID, err)
+				g.deny(w, http.StatusInternalServerError, "internal_error", "authorization check failed")
+				return
+			}
+			if !HasRole(roles, role) {
+				logger.FromContext(r.Context(), g.log).Warn("rbac: forbidden user=%s role=%s path=%s", userID, role, r.URL.Path)
+				g.deny(w, http.StatusForbidden, "forbidden", fmt.Sprintf("role %q required", role))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission returns middleware that only admits subjects granted perm.
+func (g *Guard) RequirePermission(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := SubjectFromContext(r.Context())
+			if !ok {
+				g.deny(w, http.StatusUnauthorized, "unauthorized", "authentication required")
+				return
+			}
+			perms, err := g.store.PermissionsFor(userID)
+			if err != nil {
+				logger.FromContext(r.Context(), g.log).Error("rbac: failed to load permissions user=%s: %v", userID, err)
+				g.deny(w, http.StatusInternalServerError, "internal_error", "authorization check failed")
+				return
+			}
+			if !HasPermission(perms, perm) {
+				logger.FromContext(r.Context(), g.log).Warn("rbac: forbidden user=%s permission=%s path=%s", userID, perm, r.URL.Path)
+				g.deny(w, http.StatusForbidden, "forbidden", fmt.Sprintf("permission %q required", perm))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Permission wraps a single handler with RequirePermission, so routes can
+// declare their requirement inline:
+//
+//	mux.Handle("/things", guard.Permission("things.write", thingsHandler))
+func (g *Guard) Permission(perm string, h http.Handler) http.Handler {
+	return g.RequirePermission(perm)(h)
+}
+
+// Role wraps a single handler with RequireRole.
+func (g *Guard) Role(role string, h http.Handler) http.Handler {
+	return g.RequireRole(role)(h)
+}
+
+func (g *Guard) deny(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   code,
+		"message": msg,
+	})
+}
@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeStore serves fixed role and permission lookups.
+type fakeStore struct {
+	Store
+	roles map[string][]string
+	perms map[string][]string
+}
+
+func (f *fakeStore) RolesFor(userID string) ([]string, error) {
+	return f.roles[userID], nil
+}
+
+func (f *fakeStore) PermissionsFor(userID string) ([]string, error) {
+	return f.perms[userID], nil
+}
+
+func TestGuard(t *testing.T) {
+	fs := &fakeStore{
+		roles: map[string][]string{"alice": {"admin"}, "bob": {"viewer"}},
+		perms: map[string][]string{"alice": {Wildcard}, "bob": {"things.read"}},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		wrap       func(*Guard) http.Handler
+		user       string
+		wantStatus int
+		wantType   string
+	}{
+		{"role granted", func(g *Guard) http.Handler { return g.Role("admin", ok) }, "alice", http.StatusOK, ""},
+		{"role denied", func(g *Guard) http.Handler { return g.Role("admin", ok) }, "bob", http.StatusForbidden, "application/json"},
+		{"no subject", func(g *Guard) http.Handler { return g.Role("admin", ok) }, "", http.StatusUnauthorized, "application/json"},
+		{"permission granted", func(g *Guard) http.Handler { return g.Permission("things.read", ok) }, "bob", http.StatusOK, ""},
+		{"permission wildcard", func(g *Guard) http.Handler { return g.Permission("things.write", ok) }, "alice", http.StatusOK, ""},
+		{"permission denied", func(g *Guard) http.Handler { return g.Permission("things.write", ok) }, "bob", http.StatusForbidden, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuard(fs, nil)
+			req := httptest.NewRequest(http.MethodGet, "/things", nil)
+			if tt.user != "" {
+				req = req.WithContext(WithSubject(context.Background(), tt.user))
+			}
+			rec := httptest.NewRecorder()
+			tt.wrap(g).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.wantType)
+			}
+			if tt.wantType == "application/json" {
+				var body map[string]string
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("invalid JSON error body: %v", err)
+				}
+				if body["error"] == "" || body["message"] == "" {
+					t.Errorf("error body missing fields: %v", body)
+				}
+			}
+		})
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	if !HasPermission([]string{"a", "b"}, "b") {
+		t.Error("expected exact match")
+	}
+	if !HasPermission([]string{Wildcard}, "anything") {
+		t.Error("expected wildcard match")
+	}
+	if HasPermission(nil, "a") {
+		t.Error("expected no match on empty set")
+	}
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/maloquacious/goobtool/internal/rbac"
+)
+
+// RBACStore implements the rbac.Store interface on a PostgreSQL pool
+// shared with the main datastore.
+type RBACStore struct {
+	db *sql.DB
+}
+
+// New creates a new RBACStore using an already opened database.
+func New(db *sql.DB) *RBACStore {
+	return &RBACStore{db: db}
+}
+
+// InitSchema creates the RBAC tables if they do not exist.
+func (s *RBACStore) InitSchema() error {
+	if _, err := s.db.Exec(rbacSchema); err != nil {
+		return fmt.Errorf("failed to create rbac schema: %w", err)
+	}
+	return nil
+}
+
+// CreateRole adds a new role.
+func (s *RBACStore) CreateRole(name, description string) error {
+	res, err := s.db.Exec(`INSERT INTO rbac_roles (name, description, created_at) VALUES ($1, $2, EXTRACT(EPOCH FROM now())::BIGINT) ON CONFLICT(name) DO NOTHING`, name, description)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return rbac.ErrRoleExists
+	}
+	return nil
+}
+
+// DeleteRole removes a role along with its grants and assignments.
+// Child rows go through ON DELETE CASCADE.
+func (s *RBACStore) DeleteRole(name string) error {
+	res, err := s.db.Exec(`DELETE FROM rbac_roles WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return rbac.ErrRoleNotFound
+	}
+	return nil
+}
+
+// ListRoles returns all roles with their permissions, ordered by name.
+func (s *RBACStore) ListRoles() ([]rbac.Role, error) {
+	rows, err := s.db.Query(`
+		SELECT r.name, r.description, COALESCE(p.permission, '')
+		FROM rbac_roles r
+		LEFT JOIN rbac_role_permissions p ON p.role = r.name
+		ORDER BY r.name, p.permission`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []rbac.Role
+	for rows.Next() {
+		var name, description, perm string
+		if err := rows.Scan(&name, &description, &perm); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, rbac.Role{Name: name, Description: description})
+		}
+		if perm != "" {
+			roles[len(roles)-1].Permissions = append(roles[len(roles)-1].Permissions, perm)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Grant adds a permission to a role.
+func (s *RBACStore) Grant(role, permission string) error {
+	if err := s.roleExists(role); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT INTO rbac_role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`, role, permission); err != nil {
+		return fmt.Errorf("failed to grant permission: %w", err)
+	}
+	return nil
+}
+
+// Revoke removes a permission from a role.
+func (s *RBACStore) Revoke(role, permission string) error {
+	if err := s.roleExists(role); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`DELETE FROM rbac_role_permissions WHERE role = $1 AND permission = $2`, role, permission); err != nil {
+		return fmt.Errorf("failed to revoke permission: %w", err)
+	}
+	return nil
+}
+
+// Assign gives a role to a user.
+func (s *RBACStore) Assign(userID, role string) error {
+	if err := s.roleExists(role); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT INTO rbac_user_roles (user_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, role); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// Unassign removes a role from a user.
+func (s *RBACStore) Unassign(userID, role string) error {
+	if _, err := s.db.Exec(`DELETE FROM rbac_user_roles WHERE user_id = $1 AND role = $2`, userID, role); err != nil {
+		return fmt.Errorf("failed to unassign role: %w", err)
+	}
+	return nil
+}
+
+// RolesFor returns the roles assigned to a user.
+func (s *RBACStore) RolesFor(userID string) ([]string, error) {
+	return s.strings(`SELECT role FROM rbac_user_roles WHERE user_id = $1 ORDER BY role`, userID)
+}
+
+// PermissionsFor returns the permissions granted to a user through their roles.
+func (s *RBACStore) PermissionsFor(userID string) ([]string, error) {
+	return s.strings(`
+		SELECT DISTINCT p.permission
+		FROM rbac_user_roles u
+		JOIN rbac_role_permissions p ON p.role = u.role
+		WHERE u.user_id = $1
+		ORDER BY p.permission`, userID)
+}
+
+func (s *RBACStore) roleExists(role string) error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM rbac_roles WHERE name = $1`, role).Scan(&count); err != nil {
+		return fmt.Errorf("failed to look up role: %w", err)
+	}
+	if count == 0 {
+		return rbac.ErrRoleNotFound
+	}
+	return nil
+}
+
+func (s *RBACStore) strings(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rbac: %w", err)
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to scan rbac row: %w", err)
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query rbac: %w", err)
+	}
+	return out, nil
+}
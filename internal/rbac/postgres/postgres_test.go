@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/rbac"
+	"github.com/maloquacious/goobtool/internal/store/postgres/pgtest"
+)
+
+func openTestStore(t *testing.T) *RBACStore {
+	t.Helper()
+	db, err := sql.Open("pgx", pgtest.DSN(t))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s := New(db)
+	if err := s.InitSchema(); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	// InitSchema must be idempotent
+	if err := s.InitSchema(); err != nil {
+		t.Fatalf("second InitSchema failed: %v", err)
+	}
+	return s
+}
+
+func TestRBACStore(t *testing.T) {
+	s := openTestStore(t)
+
+	if err := s.CreateRole("editor", "edits things"); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if err := s.CreateRole("editor", ""); !errors.Is(err, rbac.ErrRoleExists) {
+		t.Errorf("duplicate CreateRole: got %v, want ErrRoleExists", err)
+	}
+	if err := s.CreateRole("viewer", ""); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+
+	for _, g := range [][2]string{{"editor", "things.write"}, {"editor", "things.read"}, {"viewer", "things.read"}} {
+		if err := s.Grant(g[0], g[1]); err != nil {
+			t.Fatalf("Grant(%s, %s) failed: %v", g[0], g[1], err)
+		}
+	}
+	if err := s.Grant("missing", "x"); !errors.Is(err, rbac.ErrRoleNotFound) {
+		t.Errorf("Grant on missing role: got %v, want ErrRoleNotFound", err)
+	}
+
+	if err := s.Assign("alice", "editor"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err := s.Assign("alice", "viewer"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	roles, err := s.RolesFor("alice")
+	if err != nil {
+		t.Fatalf("RolesFor failed: %v", err)
+	}
+	if want := []string{"editor", "viewer"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("RolesFor: got %v, want %v", roles, want)
+	}
+
+	perms, err := s.PermissionsFor("alice")
+	if err != nil {
+		t.Fatalf("PermissionsFor failed: %v", err)
+	}
+	if want := []string{"things.read", "things.write"}; !reflect.DeepEqual(perms, want) {
+		t.Errorf("PermissionsFor: got %v, want %v", perms, want)
+	}
+
+	list, err := s.ListRoles()
+	if err != nil {
+		t.Fatalf("ListRoles failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "editor" || len(list[0].Permissions) != 2 {
+		t.Errorf("ListRoles: got %+v", list)
+	}
+
+	if err := s.Revoke("editor", "things.write"); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := s.DeleteRole("viewer"); err != nil {
+		t.Fatalf("DeleteRole failed: %v", err)
+	}
+	if err := s.DeleteRole("viewer"); !errors.Is(err, rbac.ErrRoleNotFound) {
+		t.Errorf("second DeleteRole: got %v, want ErrRoleNotFound", err)
+	}
+
+	roles, _ = s.RolesFor("alice")
+	if want := []string{"editor"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("RolesFor after delete: got %v, want %v", roles, want)
+	}
+	perms, _ = s.PermissionsFor("alice")
+	if want := []string{"things.read"}; !reflect.DeepEqual(perms, want) {
+		t.Errorf("PermissionsFor after revoke: got %v, want %v", perms, want)
+	}
+
+	if err := s.Unassign("alice", "editor"); err != nil {
+		t.Fatalf("Unassign failed: %v", err)
+	}
+	if roles, _ := s.RolesFor("alice"); len(roles) != 0 {
+		t.Errorf("RolesFor after unassign: got %v, want none", roles)
+	}
+}
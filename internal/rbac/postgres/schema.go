@@ -0,0 +1,25 @@
+package postgres
+
+// rbacSchema holds the role, grant and assignment tables.
+// Statements are idempotent so InitSchema can run on every startup.
+const rbacSchema = `
+CREATE TABLE IF NOT EXISTS rbac_roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rbac_role_permissions (
+    role TEXT NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS rbac_user_roles (
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS rbac_user_roles_role ON rbac_user_roles(role);
+`
@@ -0,0 +1,82 @@
+// Package rbac implements role-based access control for Goob applications.
+//
+// Roles, their permissions and user assignments live in the datastore behind
+// the Store contract. Guards wrap HTTP handlers and reject requests whose
+// subject lacks the required role or permission, answering in the response
+// style of the route group (JSON for API routes, HTML for public pages).
+package rbac
+
+import (
+	"errors"
+)
+
+// Wildcard is the permission that grants every other permission.
+const Wildcard = "*"
+
+// Errors returned by Store implementations.
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+)
+
+// Role is a named set of permissions.
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// Store defines the Goob RBAC contract.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// InitSchema creates the RBAC tables if they do not exist
+	InitSchema() error
+
+	// CreateRole adds a new role; returns ErrRoleExists if the name is taken
+	CreateRole(name, description string) error
+
+	// DeleteRole removes a role along with its grants and assignments
+	DeleteRole(name string) error
+
+	// ListRoles returns all roles with their permissions, ordered by name
+	ListRoles() ([]Role, error)
+
+	// Grant adds a permission to a role
+	Grant(role, permission string) error
+
+	// Revoke removes a permission from a role
+	Revoke(role, permission string) error
+
+	// Assign gives a role to a user
+	Assign(userID, role string) error
+
+	// Unassign removes a role from a user
+	Unassign(userID, role string) error
+
+	// RolesFor returns the roles assigned to a user, ordered by name
+	RolesFor(userID string) ([]string, error)
+
+	// PermissionsFor returns the distinct permissions granted to a user
+	// through all of their roles, ordered by name
+	PermissionsFor(userID string) ([]string, error)
+}
+
+// HasRole reports whether roles contains role.
+func HasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether perms contains perm or the Wildcard.
+func HasPermission(perms []string, perm string) bool {
+	for _, p := range perms {
+		if p == perm || p == Wildcard {
+			return true
+		}
+	}
+	return false
+}
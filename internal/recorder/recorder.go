@@ -0,0 +1,55 @@
+// Package recorder wraps an http.ResponseWriter to capture the status code
+// and body size written by a handler, for middleware that reports on
+// responses (access log, metrics, tracing).
+package recorder
+
+import "net/http"
+
+// ResponseWriter records the first status code and the number of body bytes
+// written through it.
+type ResponseWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+// Wrap returns a ResponseWriter that records what is written to w.
+func Wrap(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code sent, or 200 if the handler wrote nothing.
+func (w *ResponseWriter) Status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// Bytes returns the number of body bytes written.
+func (w *ResponseWriter) Bytes() int64 {
+	return w.bytes
+}
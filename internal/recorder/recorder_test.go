@@ -0,0 +1,41 @@
+package recorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantCode  int
+		wantBytes int64
+	}{
+		{"nothing written", func(w http.ResponseWriter, r *http.Request) {}, http.StatusOK, 0},
+		{"implicit header", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }, http.StatusOK, 5},
+		{"first header wins", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("gone"))
+		}, http.StatusNotFound, 4},
+		{"header after body", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+			w.WriteHeader(http.StatusTeapot)
+		}, http.StatusOK, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := Wrap(httptest.NewRecorder())
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Status() != tt.wantCode || rec.Bytes() != tt.wantBytes {
+				t.Errorf("Status, Bytes = %d, %d; want %d, %d", rec.Status(), rec.Bytes(), tt.wantCode, tt.wantBytes)
+			}
+		})
+	}
+
+	if http.NewResponseController(Wrap(httptest.NewRecorder())).Flush() != nil {
+		t.Error("Flush did not reach the underlying writer")
+	}
+}
@@ -0,0 +1,170 @@
+// Package render executes html/template pages built from a base layout,
+// shared partials and per-page templates. HTMX requests get just the page's
+// content fragment; everything else gets the full page.
+//
+// The template file system is laid out as
+//
+//	layouts/*.html   define "base", which wraps {{template "content" .}}
+//	partials/*.html  define named templates shared by every page (optional)
+//	pages/*.html     one file per page, each defining "content"
+//
+// A page is named after its file without the extension, so pages/index.html
+// is "index". Templates execute with a Page as dot: the handler's data is
+// .Data and the request is .Request, for functions that need per-request
+// values such as a CSP nonce.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	// LayoutTemplate is the full-page template defined by the layouts.
+	LayoutTemplate = "base"
+
+	// ContentTemplate is the fragment every page defines.
+	ContentTemplate = "content"
+)
+
+// Options configures a Renderer.
+type Options struct {
+	// FS holds the layouts, partials and pages.
+	FS fs.FS
+	// Funcs are available to every template.
+	Funcs template.FuncMap
+	// Reload parses FS again on every render so edits show up without a
+	// restart. It is meant for development; otherwise templates are parsed
+	// once by New.
+	Reload bool
+}
+
+// Page is the dot of every page and partial.
+type Page struct {
+	Data    any
+	Request *http.Request
+}
+
+// Renderer renders pages and partials.
+type Renderer struct {
+	opts Options
+	set  *templateSet // nil when reloading
+}
+
+// templateSet is one parse of the template file system.
+type templateSet struct {
+	partials *template.Template            // layouts and partials only
+	pages    map[string]*template.Template // each page with layouts and partials
+}
+
+// New parses the templates in opts.FS. Parse errors are reported here even
+// when Reload is set.
+func New(opts Options) (*Renderer, error) {
+	set, err := parse(opts.FS, opts.Funcs)
+	if err != nil {
+		return nil, err
+	}
+	r := &Renderer{opts: opts}
+	if !opts.Reload {
+		r.set = set
+	}
+	return r, nil
+}
+
+func parse(fsys fs.FS, funcs template.FuncMap) (*templateSet, error) {
+	root := template.New("").Funcs(funcs)
+	for _, pattern := range []string{"layouts/*.html", "partials/*.html"} {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if _, err := root.ParseFS(fsys, matches...); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", pattern, err)
+		}
+	}
+	if root.Lookup(LayoutTemplate) == nil {
+		return nil, fmt.Errorf("no layout defines %q", LayoutTemplate)
+	}
+
+	pages, err := fs.Glob(fsys, "pages/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %w", err)
+	}
+	set := &templateSet{partials: root, pages: make(map[string]*template.Template, len(pages))}
+	for _, file := range pages {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		t, err := template.Must(root.Clone()).ParseFS(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page %s: %w", name, err)
+		}
+		if t.Lookup(ContentTemplate) == nil {
+			return nil, fmt.Errorf("page %s does not define %q", name, ContentTemplate)
+		}
+		set.pages[name] = t
+	}
+	return set, nil
+}
+
+func (r *Renderer) templates() (*templateSet, error) {
+	if r.set != nil {
+		return r.set, nil
+	}
+	return parse(r.opts.FS, r.opts.Funcs)
+}
+
+// IsFragment reports whether req asks for a fragment: it was sent by HTMX
+// and is not HTMX restoring a page missing from its history cache.
+func IsFragment(req *http.Request) bool {
+	return req.Header.Get("HX-Request") == "true" && req.Header.Get("HX-History-Restore-Request") != "true"
+}
+
+// Render writes page name with data: the "content" fragment when
+// IsFragment(req), the full layout otherwise. Nothing is written if the
+// template fails, so the caller can still send an error response.
+func (r *Renderer) Render(w http.ResponseWriter, req *http.Request, name string, data any) error {
+	set, err := r.templates()
+	if err != nil {
+		return err
+	}
+	t, ok := set.pages[name]
+	if !ok {
+		return fmt.Errorf("unknown page %q", name)
+	}
+	tmpl := LayoutTemplate
+	if IsFragment(req) {
+		tmpl = ContentTemplate
+	}
+	w.Header().Add("Vary", "HX-Request")
+	return execute(w, t, tmpl, Page{Data: data, Request: req})
+}
+
+// Partial writes the partial called name with data, for handlers that only
+// ever answer HTMX swaps.
+func (r *Renderer) Partial(w http.ResponseWriter, req *http.Request, name string, data any) error {
+	set, err := r.templates()
+	if err != nil {
+		return err
+	}
+	if set.partials.Lookup(name) == nil {
+		return fmt.Errorf("unknown partial %q", name)
+	}
+	return execute(w, set.partials, name, Page{Data: data, Request: req})
+}
+
+func execute(w http.ResponseWriter, t *template.Template, name string, page Page) error {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, page); err != nil {
+		return fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
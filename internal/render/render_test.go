@@ -0,0 +1,164 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`{{define "base"}}<html><title>{{block "title" .}}App{{end}}</title><body>{{template "content" .}}{{template "footer" .}}</body></html>{{end}}`)},
+		"partials/footer.html": {Data: []byte(`{{define "footer"}}<footer>{{shout "bye"}}</footer>{{end}}`)},
+		"partials/greet.html":  {Data: []byte(`{{define "greet"}}<p>hi {{.Data}}</p>{{end}}`)},
+		"pages/index.html":     {Data: []byte(`{{define "title"}}Home{{end}}{{define "content"}}<main>hello {{.Data}}</main>{{end}}`)},
+		"pages/about.html":     {Data: []byte(`{{define "content"}}<main>about</main>{{end}}`)},
+	}
+}
+
+var testFuncs = template.FuncMap{"shout": strings.ToUpper}
+
+func render(t *testing.T, r *Renderer, req *http.Request, page string, data any) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := r.Render(rec, req, page, data); err != nil {
+		t.Fatalf("Render(%s) failed: %v", page, err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if v := rec.Header().Get("Vary"); v != "HX-Request" {
+		t.Errorf("Vary = %q, want HX-Request", v)
+	}
+	return rec.Body.String()
+}
+
+func TestRender(t *testing.T) {
+	r, err := New(Options{FS: testFS(), Funcs: testFuncs})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	full := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got, want := render(t, r, full, "index", "<you>"), `<html><title>Home</title><body><main>hello &lt;you&gt;</main><footer>BYE</footer></body></html>`; got != want {
+		t.Errorf("full page =\n%s\nwant\n%s", got, want)
+	}
+	if got := render(t, r, full, "about", nil); !strings.Contains(got, "<title>App</title>") {
+		t.Errorf("about page did not get the default title: %s", got)
+	}
+
+	htmx := httptest.NewRequest(http.MethodGet, "/", nil)
+	htmx.Header.Set("HX-Request", "true")
+	if got, want := render(t, r, htmx, "index", "you"), `<main>hello you</main>`; got != want {
+		t.Errorf("fragment = %q, want %q", got, want)
+	}
+
+	// history restores need the whole page
+	htmx.Header.Set("HX-History-Restore-Request", "true")
+	if got := render(t, r, htmx, "index", "you"); !strings.HasPrefix(got, "<html>") {
+		t.Errorf("history restore got a fragment: %q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := r.Partial(rec, htmx, "greet", "there"); err != nil || rec.Body.String() != "<p>hi there</p>" {
+		t.Errorf("Partial = %q, %v", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := r.Render(rec, full, "missing", nil); err == nil {
+		t.Error("Render of an unknown page succeeded")
+	}
+	if err := r.Partial(rec, full, "missing", nil); err == nil {
+		t.Error("Partial of an unknown partial succeeded")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("failed renders wrote %q", rec.Body.String())
+	}
+}
+
+func TestRenderRequest(t *testing.T) {
+	fsys := testFS()
+	fsys["pages/path.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{.Request.URL.Path}}{{end}}`)}
+	r, err := New(Options{FS: fsys, Funcs: testFuncs})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/some/where", nil)
+	req.Header.Set("HX-Request", "true")
+	if got := render(t, r, req, "path", nil); got != "/some/where" {
+		t.Errorf("page saw request path %q", got)
+	}
+}
+
+func TestRenderExecError(t *testing.T) {
+	fsys := testFS()
+	fsys["pages/broken.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}<p>partial output</p>{{.Data.Missing}}{{end}}`)}
+	r, err := New(Options{FS: fsys, Funcs: testFuncs})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if err := r.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "broken", struct{}{}); err == nil {
+		t.Fatal("Render succeeded")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("failed render wrote %q", rec.Body.String())
+	}
+}
+
+func TestReload(t *testing.T) {
+	fsys := testFS()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	cached, err := New(Options{FS: fsys, Funcs: testFuncs})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	reloading, err := New(Options{FS: fsys, Funcs: testFuncs, Reload: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}<main>edited</main>{{end}}`)}
+	fsys["pages/new.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}<main>new</main>{{end}}`)}
+
+	if got := render(t, cached, req, "index", "you"); !strings.Contains(got, "hello you") {
+		t.Errorf("cached renderer picked up an edit: %s", got)
+	}
+	if got := render(t, reloading, req, "index", "you"); !strings.Contains(got, "edited") {
+		t.Errorf("reloading renderer missed an edit: %s", got)
+	}
+	if got := render(t, reloading, req, "new", nil); !strings.Contains(got, "new") {
+		t.Errorf("reloading renderer missed a new page: %s", got)
+	}
+
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{if}}{{end}}`)}
+	if err := reloading.Render(httptest.NewRecorder(), req, "index", nil); err == nil {
+		t.Error("reloading renderer ignored a parse error")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for name, change := range map[string]func(fstest.MapFS){
+		"no layout": func(fsys fstest.MapFS) { delete(fsys, "layouts/base.html") },
+		"no content": func(fsys fstest.MapFS) {
+			fsys["pages/bad.html"] = &fstest.MapFile{Data: []byte(`{{define "other"}}{{end}}`)}
+		},
+		"parse error": func(fsys fstest.MapFS) {
+			fsys["pages/bad.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{end`)}
+		},
+		"unknown func": func(fsys fstest.MapFS) {
+			fsys["pages/bad.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{nope}}{{end}}`)}
+		},
+		"partial syntax": func(fsys fstest.MapFS) { fsys["partials/bad.html"] = &fstest.MapFile{Data: []byte(`{{define}}`)} },
+	} {
+		fsys := testFS()
+		change(fsys)
+		if _, err := New(Options{FS: fsys, Funcs: testFuncs}); err == nil {
+			t.Errorf("%s: New succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package requestid tags every HTTP request with an ID that is echoed in
+// the response and attached to log lines written through the request context.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+const (
+	// Header carries the request ID in both directions.
+	Header = "X-Request-ID"
+
+	// LogField is the log field name the ID is recorded under.
+	LogField = "request_id"
+
+	maxLen = 128
+)
+
+type idKey struct{}
+
+// Middleware accepts a well-formed incoming X-Request-ID or generates a new
+// one, echoes it in the response, and stores it in the request context for
+// FromContext and logger.FromContext.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		ctx := context.WithValue(r.Context(), idKey{}, id)
+		ctx = logger.ContextWith(ctx, LogField, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the request ID stored by Middleware, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// New returns a random 128-bit request ID in hex.
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// valid accepts IDs from upstream proxies that are short and consist of
+// characters safe to log and echo: letters, digits, '-', '_', '.', ':'.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when absent", "", false},
+		{"accepted from upstream", "edge-7f3a.42:1", true},
+		{"replaced when unsafe", "bad id\nwith newline", false},
+		{"replaced when too long", strings.Repeat("a", maxLen+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			var fields []any
+			h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = FromContext(r.Context())
+				fields = logger.ContextFields(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(Header, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(Header)
+			if got == "" || got != ctxID {
+				t.Fatalf("response ID %q does not match context ID %q", got, ctxID)
+			}
+			if tt.keep && got != tt.incoming {
+				t.Errorf("got %q, want incoming %q", got, tt.incoming)
+			}
+			if !tt.keep && got == tt.incoming {
+				t.Errorf("incoming ID %q should have been replaced", tt.incoming)
+			}
+			if len(fields) != 2 || fields[0] != LogField || fields[1] != got {
+				t.Errorf("log fields = %v, want [%s %s]", fields, LogField, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package secheaders
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+// maxReportBytes bounds a report body; browsers send a few hundred bytes.
+const maxReportBytes = 64 << 10
+
+// Violation is the part of a CSP violation report that gets logged.
+type Violation struct {
+	DocumentURL string
+	Directive   string
+	BlockedURL  string
+	SourceFile  string
+	Line        int
+	Disposition string // "enforce" or "report"
+}
+
+// legacyReport is the report-uri format (application/csp-report).
+type legacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is one entry of a Reporting API batch
+// (application/reports+json).
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// ParseReport decodes a report-uri or Reporting API request body.
+// Reporting API entries other than CSP violations are skipped.
+func ParseReport(contentType string, body []byte) ([]Violation, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/reports+json" {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		var out []Violation
+		for _, r := range batch {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			out = append(out, Violation{
+				DocumentURL: r.Body.DocumentURL,
+				Directive:   r.Body.EffectiveDirective,
+				BlockedURL:  r.Body.BlockedURL,
+				SourceFile:  r.Body.SourceFile,
+				Line:        r.Body.LineNumber,
+				Disposition: r.Body.Disposition,
+			})
+		}
+		return out, nil
+	}
+
+	var r legacyReport
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+	directive := r.Report.EffectiveDirective
+	if directive == "" {
+		directive = r.Report.ViolatedDirective
+	}
+	return []Violation{{
+		DocumentURL: r.Report.DocumentURI,
+		Directive:   directive,
+		BlockedURL:  r.Report.BlockedURI,
+		SourceFile:  r.Report.SourceFile,
+		Line:        r.Report.LineNumber,
+		Disposition: r.Report.Disposition,
+	}}, nil
+}
+
+// ReportHandler accepts CSP violation reports posted by browsers and logs
+// each violation as a warning. It answers 204 so browsers do not retry.
+func ReportHandler(log logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportBytes))
+		if err != nil {
+			http.Error(w, "report too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		violations, err := ParseReport(r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(w, "invalid report", http.StatusBadRequest)
+			return
+		}
+		l := logger.FromContext(r.Context(), log)
+		for _, v := range violations {
+			logger.With(l,
+				"document", v.DocumentURL,
+				"directive", v.Directive,
+				"blocked", v.BlockedURL,
+				"source", v.SourceFile,
+				"line", v.Line,
+				"disposition", v.Disposition,
+			).Warn("csp violation")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
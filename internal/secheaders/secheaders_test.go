@@ -0,0 +1,163 @@
+package secheaders
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+func serve(t *testing.T, opts Options, req *http.Request) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	var nonce string
+	h := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = Nonce(r.Context())
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec, nonce
+}
+
+func TestMiddleware(t *testing.T) {
+	opts := Options{
+		Policy:            DefaultPolicy,
+		ReportURI:         "/csp-report",
+		FrameAncestors:    DefaultFrameAncestors,
+		HSTSMaxAge:        24 * time.Hour,
+		ReferrerPolicy:    DefaultReferrerPolicy,
+		PermissionsPolicy: DefaultPermissionsPolicy,
+	}
+	rec, nonce := serve(t, opts, httptest.NewRequest(http.MethodGet, "/", nil))
+	h := rec.Header()
+
+	if nonce == "" {
+		t.Fatal("no nonce in the request context")
+	}
+	csp := h.Get("Content-Security-Policy")
+	for _, want := range []string{
+		"script-src 'self' 'nonce-" + nonce + "' 'unsafe-eval'",
+		"style-src 'self' 'nonce-" + nonce + "'",
+		"frame-ancestors 'none'",
+		"report-uri /csp-report",
+		"report-to csp",
+	} {
+		if !strings.Contains(csp, want) {
+			t.Errorf("policy %q lacks %q", csp, want)
+		}
+	}
+	if strings.Contains(csp, NonceSource) {
+		t.Errorf("policy kept the placeholder: %q", csp)
+	}
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           DefaultReferrerPolicy,
+		"Permissions-Policy":        DefaultPermissionsPolicy,
+		"Reporting-Endpoints":       `csp="/csp-report"`,
+		"Strict-Transport-Security": "",
+	} {
+		if got := h.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	_, again := serve(t, opts, httptest.NewRequest(http.MethodGet, "/", nil))
+	if again == nonce {
+		t.Error("nonce was reused across requests")
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	opts := Options{HSTSMaxAge: 365 * 24 * time.Hour, HSTSIncludeSubdomains: true}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec, nonce := serve(t, opts, req)
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("HSTS = %q", got)
+	}
+	if nonce != "" || rec.Header().Get("Content-Security-Policy") != "" {
+		t.Error("a policy was set without Options.Policy")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if rec, _ := serve(t, opts, req); rec.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("no HSTS behind a TLS-terminating proxy")
+	}
+	if rec, _ := serve(t, Options{}, req); rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS sent with a zero max-age")
+	}
+}
+
+func TestReportOnly(t *testing.T) {
+	rec, _ := serve(t, Options{Policy: "default-src 'self'", ReportOnly: true, FrameAncestors: "'self'"}, httptest.NewRequest(http.MethodGet, "/", nil))
+	h := rec.Header()
+	if h.Get("Content-Security-Policy") != "" {
+		t.Error("report-only mode sent an enforced policy")
+	}
+	if got := h.Get("Content-Security-Policy-Report-Only"); got != "default-src 'self'; frame-ancestors 'self'" {
+		t.Errorf("report-only policy = %q", got)
+	}
+	if got := h.Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q", got)
+	}
+}
+
+func TestSetDirective(t *testing.T) {
+	policy := "default-src 'self'; script-src 'self'"
+	if got, want := SetDirective(policy, "img-src", "'self' data:"), policy+"; img-src 'self' data:"; got != want {
+		t.Errorf("SetDirective of a new directive = %q, want %q", got, want)
+	}
+	if got, want := SetDirective(policy+";", "SCRIPT-SRC", "'none'"), "default-src 'self'; SCRIPT-SRC 'none'"; got != want {
+		t.Errorf("SetDirective = %q, want %q", got, want)
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := ReportHandler(logger.NewSlogLogger(&buf, logger.FormatText, slog.LevelInfo))
+
+	post := func(contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	legacy := `{"csp-report":{"document-uri":"https://example.test/","violated-directive":"script-src-elem","blocked-uri":"https://evil.test/x.js","disposition":"enforce"}}`
+	if code := post("application/csp-report", legacy); code != http.StatusNoContent {
+		t.Errorf("legacy report: status %d", code)
+	}
+	api := `[{"type":"csp-violation","body":{"documentURL":"https://example.test/a","effectiveDirective":"style-src-attr","blockedURL":"inline","disposition":"report"}},{"type":"deprecation","body":{}}]`
+	if code := post("application/reports+json", api); code != http.StatusNoContent {
+		t.Errorf("reporting API report: status %d", code)
+	}
+	out := buf.String()
+	for _, want := range []string{"https://evil.test/x.js", "script-src-elem", "style-src-attr", "disposition=report"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "csp violation"); n != 2 {
+		t.Errorf("logged %d violations, want 2", n)
+	}
+
+	if code := post("application/csp-report", "{"); code != http.StatusBadRequest {
+		t.Errorf("malformed report: status %d", code)
+	}
+	if code := post("application/csp-report", strings.Repeat(" ", maxReportBytes+1)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized report: status %d", code)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/csp-report", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", rec.Code)
+	}
+}
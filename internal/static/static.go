@@ -0,0 +1,272 @@
+// Package static serves a tree of static files with content-hash caching.
+//
+// Every file is hashed when the Handler is built. A file is reachable at
+// its own path, where responses carry a strong ETag and must be revalidated,
+// and at a fingerprinted path with the hash before the extension
+// (css/site.css as css/site.0123456789.css), where responses may be cached
+// for a year. URL returns the fingerprinted path for templates.
+//
+// When a file has precompressed siblings (site.css.br, site.css.gz) the
+// handler serves the best one the client accepts.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ImmutableCacheControl is sent for fingerprinted paths.
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+	// RevalidateCacheControl is sent for plain paths.
+	RevalidateCacheControl = "no-cache"
+
+	fingerprintLen = 10
+)
+
+// encodings lists the precompressed variants looked for, in order of
+// preference when a client accepts several equally.
+var encodings = []struct {
+	name, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Options configures a Handler.
+type Options struct {
+	// Prefix is the URL path the handler is mounted at, e.g. "/public/".
+	// It is stripped from requests and added by URL.
+	Prefix string
+	// Reload rebuilds the index on every request so edits show up without a
+	// restart. It is meant for development.
+	Reload bool
+}
+
+// Handler serves the files of an fs.FS.
+type Handler struct {
+	fsys  fs.FS
+	opts  Options
+	index map[string]*file // nil when reloading
+}
+
+type file struct {
+	contentType string
+	fingerprint string
+	variants    []variant // identity first, then the encodings found
+}
+
+type variant struct {
+	encoding string // "" for identity
+	data     []byte
+	etag     string
+}
+
+// New hashes every file in fsys. Errors reading fsys are reported here
+// even when Reload is set.
+func New(fsys fs.FS, opts Options) (*Handler, error) {
+	if !strings.HasSuffix(opts.Prefix, "/") {
+		opts.Prefix += "/"
+	}
+	index, err := buildIndex(fsys)
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{fsys: fsys, opts: opts}
+	if !opts.Reload {
+		h.index = index
+	}
+	return h, nil
+}
+
+func buildIndex(fsys fs.FS) (map[string]*file, error) {
+	index := map[string]*file{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		f := &file{
+			contentType: mime.TypeByExtension(path.Ext(name)),
+			fingerprint: hash[:fingerprintLen],
+			variants:    []variant{{data: data, etag: strconv.Quote(hash[:32])}},
+		}
+		if f.contentType == "" {
+			f.contentType = http.DetectContentType(data)
+		}
+		index[name] = f
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index static files: %w", err)
+	}
+
+	// attach precompressed siblings to the files they compress
+	for name, f := range index {
+		for _, enc := range encodings {
+			if c, ok := index[name+enc.ext]; ok {
+				f.variants = append(f.variants, variant{
+					encoding: enc.name,
+					data:     c.variants[0].data,
+					etag:     strconv.Quote(strings.Trim(f.variants[0].etag, `"`) + "-" + enc.name),
+				})
+			}
+		}
+	}
+	return index, nil
+}
+
+func (h *Handler) files() (map[string]*file, error) {
+	if h.index != nil {
+		return h.index, nil
+	}
+	return buildIndex(h.fsys)
+}
+
+// URL returns the fingerprinted URL of the file called name.
+func (h *Handler) URL(name string) (string, error) {
+	index, err := h.files()
+	if err != nil {
+		return "", err
+	}
+	f, ok := index[name]
+	if !ok {
+		return "", fmt.Errorf("unknown static file %q", name)
+	}
+	ext := path.Ext(name)
+	return h.opts.Prefix + strings.TrimSuffix(name, ext) + "." + f.fingerprint + ext, nil
+}
+
+// lookup resolves a request path to a file and whether the path was
+// fingerprinted.
+func lookup(index map[string]*file, name string) (*file, bool) {
+	if f, ok := index[name]; ok {
+		return f, false
+	}
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	fp := path.Ext(stem)
+	if len(fp) != fingerprintLen+1 {
+		// a file without an extension ends in its fingerprint
+		fp, ext = ext, ""
+		if len(fp) != fingerprintLen+1 {
+			return nil, false
+		}
+	} else {
+		stem = strings.TrimSuffix(stem, fp)
+	}
+	f, ok := index[stem+ext]
+	if !ok || f.fingerprint != fp[1:] {
+		// an old fingerprint must not be cached as the current content
+		return nil, false
+	}
+	return f, true
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, h.opts.Prefix)
+	if !ok || !fs.ValidPath(name) {
+		http.NotFound(w, r)
+		return
+	}
+	index, err := h.files()
+	if err != nil {
+		http.Error(w, "static files unavailable", http.StatusInternalServerError)
+		return
+	}
+	f, fingerprinted := lookup(index, name)
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	v := f.variants[0]
+	if len(f.variants) > 1 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		v = negotiate(r.Header.Get("Accept-Encoding"), f.variants)
+	}
+	if fingerprinted {
+		w.Header().Set("Cache-Control", ImmutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", RevalidateCacheControl)
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("ETag", v.etag)
+	if v.encoding != "" {
+		w.Header().Set("Content-Encoding", v.encoding)
+	}
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(v.data))
+}
+
+// negotiate picks the variant with the highest quality in an
+// Accept-Encoding header, preferring the order of variants on ties.
+// Identity is used unless the client refuses it.
+func negotiate(header string, variants []variant) variant {
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if coding == "*" {
+			wildcard = q
+		} else {
+			quality[coding] = q
+		}
+	}
+	qualityOf := func(encoding string) float64 {
+		if encoding == "" {
+			encoding = "identity"
+		}
+		if q, ok := quality[encoding]; ok {
+			return q
+		}
+		if wildcard >= 0 {
+			return wildcard
+		}
+		if encoding == "identity" {
+			return 0.001 // acceptable unless refused
+		}
+		return 0
+	}
+
+	best, bestQ := variants[0], qualityOf("")
+	for _, v := range variants[1:] {
+		if q := qualityOf(v.encoding); q > 0 && q >= bestQ && (q > bestQ || best.encoding == "") {
+			best, bestQ = v, q
+		}
+	}
+	return best
+}
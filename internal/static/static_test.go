@@ -0,0 +1,175 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/site.css":    {Data: []byte("body { color: red }")},
+		"css/site.css.br": {Data: []byte("brotli bytes")},
+		"css/site.css.gz": {Data: []byte("gzip bytes")},
+		"js/app.js":       {Data: []byte("console.log(1)")},
+		"js/app.js.gz":    {Data: []byte("gzip js")},
+		"LICENSE":         {Data: []byte("MIT")},
+	}
+}
+
+func newHandler(t *testing.T, fsys fstest.MapFS, reload bool) *Handler {
+	t.Helper()
+	h, err := New(fsys, Options{Prefix: "/public", Reload: reload})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return h
+}
+
+func get(h http.Handler, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFingerprints(t *testing.T) {
+	h := newHandler(t, testFS(), false)
+
+	for _, name := range []string{"css/site.css", "js/app.js", "LICENSE"} {
+		url, err := h.URL(name)
+		if err != nil {
+			t.Fatalf("URL(%s) failed: %v", name, err)
+		}
+		if !strings.HasPrefix(url, "/public/") || url == "/public/"+name {
+			t.Errorf("URL(%s) = %q, want a fingerprinted /public/ path", name, url)
+		}
+
+		rec := get(h, url)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", url, rec.Code)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != ImmutableCacheControl {
+			t.Errorf("GET %s Cache-Control = %q", url, cc)
+		}
+
+		rec = get(h, "/public/"+name)
+		if cc := rec.Header().Get("Cache-Control"); rec.Code != http.StatusOK || cc != RevalidateCacheControl {
+			t.Errorf("GET /public/%s = %d, Cache-Control %q", name, rec.Code, cc)
+		}
+	}
+	if _, err := h.URL("missing.css"); err == nil {
+		t.Error("URL of a missing file succeeded")
+	}
+
+	// a stale or malformed fingerprint is not the current file
+	for _, target := range []string{"/public/css/site.0000000000.css", "/public/css/site.00.css", "/public/css/", "/public/../secret", "/other/js/app.js"} {
+		if rec := get(h, target); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestETags(t *testing.T) {
+	h := newHandler(t, testFS(), false)
+
+	rec := get(h, "/public/js/app.js", "Accept-Encoding", "identity")
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("ETag = %q, want a strong ETag", etag)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	rec = get(h, "/public/js/app.js", "Accept-Encoding", "identity", "If-None-Match", etag)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", rec.Code)
+	}
+
+	// each representation has its own ETag
+	rec = get(h, "/public/js/app.js", "Accept-Encoding", "gzip", "If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("gzip GET with identity ETag = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// content changes change the ETag and fingerprint
+	fsys := testFS()
+	before, _ := newHandler(t, fsys, false).URL("js/app.js")
+	fsys["js/app.js"] = &fstest.MapFile{Data: []byte("console.log(2)")}
+	h2 := newHandler(t, fsys, false)
+	after, _ := h2.URL("js/app.js")
+	if before == after {
+		t.Error("fingerprint did not change with the content")
+	}
+	if get(h2, "/public/js/app.js", "Accept-Encoding", "identity").Header().Get("ETag") == etag {
+		t.Error("ETag did not change with the content")
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	h := newHandler(t, testFS(), false)
+	for _, tc := range []struct {
+		accept, encoding, body string
+	}{
+		{"", "", "body { color: red }"},
+		{"gzip", "gzip", "gzip bytes"},
+		{"gzip, deflate, br", "br", "brotli bytes"},
+		{"br;q=0.5, gzip", "gzip", "gzip bytes"},
+		{"br;q=0, gzip;q=0", "", "body { color: red }"},
+		{"*", "br", "brotli bytes"},
+		{"identity;q=1, gzip;q=0.5", "", "body { color: red }"},
+	} {
+		rec := get(h, "/public/css/site.css", "Accept-Encoding", tc.accept)
+		if got := rec.Header().Get("Content-Encoding"); got != tc.encoding || rec.Body.String() != tc.body {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q body %q, want %q %q", tc.accept, got, rec.Body.String(), tc.encoding, tc.body)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+			t.Errorf("Accept-Encoding %q: Content-Type = %q", tc.accept, ct)
+		}
+		if v := rec.Header().Get("Vary"); v != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Vary = %q", tc.accept, v)
+		}
+	}
+
+	if rec := get(h, "/public/LICENSE", "Accept-Encoding", "gzip"); rec.Header().Get("Vary") != "" || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("file without variants got Vary %q, Content-Encoding %q", rec.Header().Get("Vary"), rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestMethods(t *testing.T) {
+	h := newHandler(t, testFS(), false)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/public/js/app.js", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST = %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/public/js/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD = %d with %d body bytes", rec.Code, rec.Body.Len())
+	}
+}
+
+func TestReload(t *testing.T) {
+	fsys := testFS()
+	cached := newHandler(t, fsys, false)
+	reloading := newHandler(t, fsys, true)
+	fsys["js/new.js"] = &fstest.MapFile{Data: []byte("new")}
+
+	if _, err := cached.URL("js/new.js"); err == nil {
+		t.Error("cached handler picked up a new file")
+	}
+	url, err := reloading.URL("js/new.js")
+	if err != nil {
+		t.Fatalf("reloading handler missed a new file: %v", err)
+	}
+	if rec := get(reloading, url); rec.Code != http.StatusOK || rec.Body.String() != "new" {
+		t.Errorf("GET %s = %d %q", url, rec.Code, rec.Body.String())
+	}
+}
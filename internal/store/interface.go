@@ -1,30 +1,95 @@
 package store
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
 // StoreState represents the initialization state of the datastore.
 type StoreState int
 
 const (
 	StateMissing         StoreState = iota // File doesn't exist
 	StateUninitialized                     // File exists but no schema
-	StateVersionMismatch                   // Schema exists but wrong version
+	StateVersionMismatch                   // Schema version cannot be compared (not semver)
 	StateReady                             // Initialized and correct version
+	StateNeedsUpgrade                      // Schema is older than the binary; db upgrade applies
+	StateTooNew                            // Schema is newer than the binary; refuse to run
 )
 
+func (s StoreState) String() string {
+	switch s {
+	case StateMissing:
+		return "missing"
+	case StateUninitialized:
+		return "uninitialized"
+	case StateVersionMismatch:
+		return "version_mismatch"
+	case StateReady:
+		return "ready"
+	case StateNeedsUpgrade:
+		return "needs_upgrade"
+	case StateTooNew:
+		return "too_new"
+	}
+	return fmt.Sprintf("StoreState(%d)", int(s))
+}
+
 // Store defines the Goob datastore contract.
-// Implementations must be safe for concurrent use.
+// Implementations must be safe for concurrent use. Methods taking a context
+// must stop waiting and return an error once it is done.
 type Store interface {
 	// Open opens the datastore connection
-	Open() error
+	Open(ctx context.Context) error
 
 	// Close closes the datastore connection
 	Close() error
 
 	// InitSchema creates the initial schema (schema_migrations table)
-	InitSchema(version string) error
+	InitSchema(ctx context.Context, version string) error
 
 	// CheckState returns the current state of the datastore
-	CheckState() (StoreState, error)
+	CheckState(ctx context.Context) (StoreState, error)
+
+	// GetSchemaVersion returns the current schema version from the database:
+	// the highest applied version by semver precedence
+	GetSchemaVersion(ctx context.Context) (string, error)
+
+	// ApplyMigration runs fn and records version in schema_migrations in
+	// one read-write transaction; see Migrate
+	ApplyMigration(ctx context.Context, version string, fn func(Tx) error) error
+
+	// WithTx runs fn in a read-write transaction, committing if fn returns nil
+	// and rolling back otherwise. The transaction is retried from the start
+	// when the backend reports it is busy, so fn must be safe to re-run.
+	WithTx(ctx context.Context, fn func(Tx) error) error
+
+	// WithReadTx runs fn in a read-only transaction; writes fail.
+	WithReadTx(ctx context.Context, fn func(Tx) error) error
+}
+
+// Tx is the transaction handed to WithTx and WithReadTx callbacks.
+// *sql.Tx satisfies it. It must not be used after the callback returns.
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Stats describes the physical state of a datastore for status reporting.
+type Stats struct {
+	SchemaVersion   string    // latest applied schema version
+	LastMigration   time.Time // when the latest schema version was applied; zero if unknown
+	SizeBytes       int64     // main database file size
+	WALSizeBytes    int64     // write-ahead log size (0 when absent)
+	PageCount       int64     // database pages in use
+	PageSize        int64     // bytes per page
+	OpenConnections int       // connections currently open in the pool
+}
 
-	// GetSchemaVersion returns the current schema version from the database
-	GetSchemaVersion() (string, error)
+// StatsReporter is implemented by stores that can report Stats.
+type StatsReporter interface {
+	Stats(ctx context.Context) (Stats, error)
 }
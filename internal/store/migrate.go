@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/maloquacious/semver"
+)
+
+// Migration upgrades the schema to Version from the version before it.
+type Migration struct {
+	Version string
+	// Up changes the schema. It runs in the transaction that records
+	// Version, so a failure leaves the datastore as it was. SQL must work
+	// on every backend the application supports.
+	Up func(ctx context.Context, tx Tx) error
+}
+
+// Migrate applies, in version order, every migration newer than the
+// datastore's schema version up to and including target, and returns the
+// versions it applied. It fails without applying anything when the
+// datastore is newer than target or the migrations do not reach target.
+// Migrations are applied one transaction at a time; after a failure the
+// datastore stays at the last version that succeeded.
+func Migrate(ctx context.Context, s Store, migrations []Migration, target string) ([]string, error) {
+	want, err := ParseVersion(target)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.GetSchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	have, err := ParseVersion(current)
+	if err != nil {
+		return nil, fmt.Errorf("cannot migrate from schema %q: %w", current, err)
+	}
+	if want.Less(have) {
+		return nil, fmt.Errorf("schema %s is newer than %s", current, target)
+	}
+
+	type step struct {
+		Migration
+		v semver.Version
+	}
+	var pending []step
+	for _, m := range migrations {
+		v, err := ParseVersion(m.Version)
+		if err != nil {
+			return nil, err
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %s has no Up", m.Version)
+		}
+		if have.Less(v) && !want.Less(v) {
+			pending = append(pending, step{m, v})
+		}
+	}
+	slices.SortFunc(pending, func(a, b step) int { return a.v.Compare(b.v) })
+	for i := 1; i < len(pending); i++ {
+		if pending[i-1].v.Compare(pending[i].v) == 0 {
+			return nil, fmt.Errorf("duplicate migration %s", pending[i].Version)
+		}
+	}
+	if have.Compare(want) != 0 && (len(pending) == 0 || pending[len(pending)-1].v.Compare(want) != 0) {
+		return nil, fmt.Errorf("no migration path from schema %s to %s", current, target)
+	}
+
+	var applied []string
+	for _, m := range pending {
+		err := s.ApplyMigration(ctx, m.Version, func(tx Tx) error { return m.Up(ctx, tx) })
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
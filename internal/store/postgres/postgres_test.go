@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/store/postgres/pgtest"
+	"github.com/maloquacious/goobtool/internal/store/storetest"
+)
+
+func newTestStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	s := New(pgtest.DSN(t), "0.1")
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) func(string) store.Store {
+		dsn := pgtest.DSN(t)
+		return func(expected string) store.Store { return New(dsn, expected) }
+	})
+}
+
+func TestStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.InitSchema(ctx, "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+
+	st, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if st.SchemaVersion != "0.1" || st.SizeBytes == 0 || st.LastMigration.IsZero() {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,10 @@
+package postgres
+
+// initialSchema mirrors the SQLite schema_migrations table so both backends
+// track versions the same way. applied_at holds Unix seconds.
+const initialSchema = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version TEXT PRIMARY KEY,
+    applied_at BIGINT NOT NULL
+);
+`
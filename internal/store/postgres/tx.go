@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/tracing"
+)
+
+// Retry policy for WithTx, matching the SQLite backend's busy retries.
+const (
+	txMaxAttempts  = 5
+	txRetryBackoff = 10 * time.Millisecond
+)
+
+// isRetryable reports whether err is a serialization failure or deadlock,
+// the PostgreSQL errors that mean "run the transaction again".
+func isRetryable(err error) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && (pe.Code == "40001" || pe.Code == "40P01")
+}
+
+// WithTx runs fn in a read-write transaction, retrying on serialization
+// failures and deadlocks.
+func (s *PostgresStore) WithTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	if s.db == nil {
+		return fmt.Errorf("database not opened")
+	}
+
+	ctx, span := s.startSpan(ctx, "WithTx", tracing.Bool("db.transaction", true))
+	attempts := 0
+	defer func() {
+		span.SetAttributes(tracing.Int("goob.tx.attempts", attempts))
+		span.RecordError(err)
+		span.End()
+	}()
+
+	backoff := txRetryBackoff
+	for {
+		attempts++
+		err = s.runTx(ctx, nil, fn)
+		if !isRetryable(err) || attempts >= txMaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("transaction retry abandoned: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// WithReadTx runs fn in a READ ONLY transaction; writes fail.
+func (s *PostgresStore) WithReadTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	if s.db == nil {
+		return fmt.Errorf("database not opened")
+	}
+
+	ctx, span := s.startSpan(ctx, "WithReadTx", tracing.Bool("db.transaction", true))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	return s.runTx(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+// runTx is a single transaction attempt.
+func (s *PostgresStore) runTx(ctx context.Context, opts *sql.TxOptions, fn func(store.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ApplyMigration runs fn and records version in schema_migrations in one
+// WithTx transaction, so a failed migration leaves no trace.
+func (s *PostgresStore) ApplyMigration(ctx context.Context, version string, fn func(store.Tx) error) error {
+	return s.WithTx(ctx, func(tx store.Tx) error {
+		if err := fn(tx); err != nil {
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, EXTRACT(EPOCH FROM now())::BIGINT)`, version); err != nil {
+			return fmt.Errorf("failed to record schema version %s: %w", version, err)
+		}
+		return nil
+	})
+}
@@ -1,18 +1,35 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"time"
 
 	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/tracing"
 	_ "modernc.org/sqlite"
 )
 
+// busyTimeout is how long SQLite waits for a lock before returning
+// SQLITE_BUSY. Tests shorten it to exercise WithTx retries.
+var busyTimeout = 5 * time.Second
+
 // SQLiteStore implements the Store interface using modernc.org/sqlite.
+//
+// Writes go through a single-connection pool so writers queue in Go rather
+// than contending for the SQLite write lock; reads use a separate read-only
+// pool that WAL mode lets run alongside the writer.
 type SQLiteStore struct {
 	dbPath         string
-	db             *sql.DB
+	db             *sql.DB // writer, one connection
+	readDB         *sql.DB // readers, mode=ro and query_only
 	expectedSchema string
+	tracer         tracing.Tracer
 }
 
 // New creates a new SQLiteStore.
@@ -20,118 +37,231 @@ func New(dbPath string, expectedSchema string) *SQLiteStore {
 	return &SQLiteStore{
 		dbPath:         dbPath,
 		expectedSchema: expectedSchema,
+		tracer:         tracing.Noop,
+	}
+}
+
+// SetTracer sets the tracer used for spans around queries and transactions.
+func (s *SQLiteStore) SetTracer(t tracing.Tracer) {
+	if t == nil {
+		t = tracing.Noop
 	}
+	s.tracer = t
 }
 
-// Open opens the SQLite database with safe defaults.
-func (s *SQLiteStore) Open() error {
-	db, err := sql.Open("sqlite", s.dbPath)
+// startSpan begins a client span for a store operation.
+func (s *SQLiteStore) startSpan(ctx context.Context, op string, attrs ...tracing.Attr) (context.Context, tracing.Span) {
+	attrs = append([]tracing.Attr{
+		tracing.String("db.system.name", "sqlite"),
+		tracing.String("db.operation.name", op),
+		tracing.String("db.namespace", s.dbPath),
+	}, attrs...)
+	return s.tracer.Start(ctx, "sqlite "+op, tracing.KindClient, attrs...)
+}
+
+// Open opens the writer and reader pools with safe defaults.
+func (s *SQLiteStore) Open(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, "Open")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	// Pragmas are applied by the driver on every new connection
+	pragmas := []string{fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()), "foreign_keys(1)"}
+
+	writeParams := url.Values{"_pragma": append([]string{"journal_mode(WAL)", "synchronous(NORMAL)"}, pragmas...)}
+	writeParams.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite", dsn(s.dbPath, writeParams))
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-
-	// Apply safe defaults
-	pragmas := []string{
-		"PRAGMA journal_mode=WAL",
-		"PRAGMA synchronous=NORMAL",
-		"PRAGMA foreign_keys=ON",
-		"PRAGMA busy_timeout=5000",
+	db.SetMaxOpenConns(1)
+	// the writer connection creates the file and the WAL before readers attach
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open database: %w", err)
 	}
 
-	for _, pragma := range pragmas {
-		if _, err := db.Exec(pragma); err != nil {
-			db.Close()
-			return fmt.Errorf("failed to set pragma %q: %w", pragma, err)
-		}
+	readParams := url.Values{"mode": {"ro"}, "_pragma": append([]string{"query_only(1)"}, pragmas...)}
+	readDB, err := sql.Open("sqlite", dsn(s.dbPath, readParams))
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open read pool: %w", err)
 	}
+	readers := max(4, runtime.GOMAXPROCS(0))
+	readDB.SetMaxOpenConns(readers)
+	readDB.SetMaxIdleConns(readers)
 
-	s.db = db
+	s.db, s.readDB = db, readDB
 	return nil
 }
 
-// Close closes the database connection.
+// dsn builds a file: URI for path. modernc.org/sqlite handles the _pragma
+// and _txlock parameters; SQLite itself handles mode.
+func dsn(path string, params url.Values) string {
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode()
+}
+
+// DB returns the writer pool so that subsystems sharing the datastore
+// (RBAC, audit, etc.) can run their own queries. It holds one connection;
+// do not keep rows open while writing through it.
+// Returns nil until Open has succeeded.
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// ReadDB returns the read-only pool. Returns nil until Open has succeeded.
+func (s *SQLiteStore) ReadDB() *sql.DB {
+	return s.readDB
+}
+
+// Close closes both connection pools.
 func (s *SQLiteStore) Close() error {
+	var errs []error
+	if s.readDB != nil {
+		errs = append(errs, s.readDB.Close())
+	}
 	if s.db != nil {
-		return s.db.Close()
+		errs = append(errs, s.db.Close())
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // InitSchema creates the initial schema with the schema_migrations table.
-func (s *SQLiteStore) InitSchema(version string) error {
+func (s *SQLiteStore) InitSchema(ctx context.Context, version string) error {
+	return s.WithTx(ctx, func(tx store.Tx) error {
+		if _, err := tx.ExecContext(ctx, initialSchema); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, strftime('%s', 'now'))`, version); err != nil {
+			return fmt.Errorf("failed to insert schema version: %w", err)
+		}
+		return nil
+	})
+}
+
+// CheckState returns the current state of the datastore.
+func (s *SQLiteStore) CheckState(ctx context.Context) (state store.StoreState, err error) {
 	if s.db == nil {
-		return fmt.Errorf("database not opened")
+		return store.StateMissing, fmt.Errorf("database not opened")
 	}
 
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
+	ctx, span := s.startSpan(ctx, "CheckState")
+	defer func() {
+		span.SetAttributes(tracing.Int("goob.store.state", int(state)))
+		span.RecordError(err)
+		span.End()
+	}()
 
-	// Create schema_migrations table
-	_, err = tx.Exec(initialSchema)
+	exists, err := s.hasMigrations(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+		return store.StateUninitialized, err
 	}
-
-	// Insert initial version
-	_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, strftime('%s', 'now'))`, version)
-	if err != nil {
-		return fmt.Errorf("failed to insert schema version: %w", err)
+	if !exists {
+		return store.StateUninitialized, nil
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Check schema version
+	version, err := s.GetSchemaVersion(ctx)
+	if err != nil {
+		return store.StateUninitialized, fmt.Errorf("failed to get schema version: %w", err)
 	}
 
-	return nil
+	return store.CompareVersions(version, s.expectedSchema), nil
 }
 
-// CheckState returns the current state of the datastore.
-func (s *SQLiteStore) CheckState() (store.StoreState, error) {
+// GetSchemaVersion returns the highest applied schema version by semver
+// precedence, so migrations applied within the same second still order.
+func (s *SQLiteStore) GetSchemaVersion(ctx context.Context) (version string, err error) {
 	if s.db == nil {
-		return store.StateMissing, fmt.Errorf("database not opened")
+		return "", fmt.Errorf("database not opened")
 	}
 
-	// Check if schema_migrations table exists
-	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'`).Scan(&count)
+	ctx, span := s.startSpan(ctx, "GetSchemaVersion")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	versions, _, err := s.appliedVersions(ctx)
 	if err != nil {
-		return store.StateUninitialized, fmt.Errorf("failed to check schema_migrations table: %w", err)
+		return "", err
 	}
+	return store.LatestVersion(versions), nil
+}
 
-	if count == 0 {
-		return store.StateUninitialized, nil
+// hasMigrations reports whether the schema_migrations table exists.
+func (s *SQLiteStore) hasMigrations(ctx context.Context) (bool, error) {
+	var count int
+	err := s.readDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check schema_migrations table: %w", err)
 	}
+	return count > 0, nil
+}
 
-	// Check schema version
-	version, err := s.GetSchemaVersion()
+// appliedVersions returns every applied schema version in the order it was
+// applied, and the time of the most recent migration in Unix seconds.
+func (s *SQLiteStore) appliedVersions(ctx context.Context) (versions []string, lastApplied int64, err error) {
+	rows, err := s.readDB.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations ORDER BY applied_at, version`)
 	if err != nil {
-		return store.StateUninitialized, fmt.Errorf("failed to get schema version: %w", err)
+		return nil, 0, fmt.Errorf("failed to query schema version: %w", err)
 	}
-
-	if version != s.expectedSchema {
-		return store.StateVersionMismatch, nil
+	defer rows.Close()
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version, &lastApplied); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan schema version: %w", err)
+		}
+		versions = append(versions, version)
 	}
-
-	return store.StateReady, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to query schema version: %w", err)
+	}
+	return versions, lastApplied, nil
 }
 
-// GetSchemaVersion returns the current schema version from the database.
-func (s *SQLiteStore) GetSchemaVersion() (string, error) {
+// Stats reports file sizes, page counts and pool usage.
+// Missing files and an uninitialized schema are reported as zero values;
+// any other failure to read the schema version is returned.
+func (s *SQLiteStore) Stats(ctx context.Context) (st store.Stats, err error) {
 	if s.db == nil {
-		return "", fmt.Errorf("database not opened")
+		return st, fmt.Errorf("database not opened")
 	}
 
-	var version string
-	err := s.db.QueryRow(`SELECT version FROM schema_migrations ORDER BY applied_at DESC LIMIT 1`).Scan(&version)
-	if err == sql.ErrNoRows {
-		return "", nil
+	ctx, span := s.startSpan(ctx, "Stats")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if info, err := os.Stat(s.dbPath); err == nil {
+		st.SizeBytes = info.Size()
 	}
-	if err != nil {
-		return "", fmt.Errorf("failed to query schema version: %w", err)
+	if info, err := os.Stat(s.dbPath + "-wal"); err == nil {
+		st.WALSizeBytes = info.Size()
+	}
+	if err := s.readDB.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&st.PageCount); err != nil {
+		return st, fmt.Errorf("failed to read page_count: %w", err)
 	}
+	if err := s.readDB.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&st.PageSize); err != nil {
+		return st, fmt.Errorf("failed to read page_size: %w", err)
+	}
+	st.OpenConnections = s.db.Stats().OpenConnections + s.readDB.Stats().OpenConnections
 
-	return version, nil
+	// no table yet: leave the schema fields empty
+	exists, err := s.hasMigrations(ctx)
+	if err != nil || !exists {
+		return st, err
+	}
+	versions, lastApplied, err := s.appliedVersions(ctx)
+	if err != nil {
+		return st, err
+	}
+	if len(versions) > 0 {
+		st.SchemaVersion = store.LatestVersion(versions)
+		st.LastMigration = time.Unix(lastApplied, 0).UTC()
+	}
+	return st, nil
 }
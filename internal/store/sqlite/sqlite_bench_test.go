@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// benchRows is the size of the table BenchmarkMixedLoad works on. Writes
+// update rows in place so every iteration sees the same table.
+const benchRows = 10_000
+
+// BenchmarkMixedLoad runs a 90/10 mix of point reads and updates by id
+// from parallel goroutines, once against a single default pool (how Open
+// used to work) and once against the split writer/reader pools.
+//
+//	go test -run=^$ -bench=MixedLoad -cpu=1,4,8 ./internal/store/sqlite
+func BenchmarkMixedLoad(b *testing.B) {
+	b.Run("shared", func(b *testing.B) {
+		params := url.Values{"_pragma": {"journal_mode(WAL)", "synchronous(NORMAL)", "busy_timeout(5000)", "foreign_keys(1)"}}
+		db, err := sql.Open("sqlite", dsn(filepath.Join(b.TempDir(), "bench.db"), params))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer db.Close()
+		seedItems(b, db)
+
+		mixedLoad(b,
+			func(ctx context.Context, fn func(store.Tx) error) error { return inTx(ctx, db, false, fn) },
+			func(ctx context.Context, fn func(store.Tx) error) error { return inTx(ctx, db, true, fn) })
+	})
+
+	b.Run("split", func(b *testing.B) {
+		s := New(filepath.Join(b.TempDir(), "bench.db"), "0.1")
+		if err := s.Open(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+		defer s.Close()
+		seedItems(b, s.DB())
+
+		mixedLoad(b, s.WithTx, s.WithReadTx)
+	})
+}
+
+// seedItems creates the items table with benchRows rows.
+func seedItems(b *testing.B, db *sql.DB) {
+	b.Helper()
+	const seed = `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		WITH RECURSIVE n(id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM n WHERE id < ?)
+		INSERT INTO items (id, name) SELECT id, 'item' FROM n`
+	if _, err := db.Exec(seed, benchRows); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// mixedLoad runs the read/write mix, sending writes through writeTx and
+// reads through readTx.
+func mixedLoad(b *testing.B, writeTx, readTx func(context.Context, func(store.Tx) error) error) {
+	const read = `SELECT name FROM items WHERE id = ?`
+	const write = `UPDATE items SET name = ? WHERE id = ?`
+
+	var n atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			id := rand.IntN(benchRows) + 1
+			var err error
+			if n.Add(1)%10 == 0 {
+				err = writeTx(ctx, func(tx store.Tx) error {
+					_, err := tx.ExecContext(ctx, write, "updated", id)
+					return err
+				})
+			} else {
+				err = readTx(ctx, func(tx store.Tx) error {
+					var name string
+					return tx.QueryRowContext(ctx, read, id).Scan(&name)
+				})
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// inTx runs fn in a transaction on db without any retry or routing.
+func inTx(ctx context.Context, db *sql.DB, readOnly bool, fn func(store.Tx) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
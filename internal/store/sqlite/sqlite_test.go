@@ -0,0 +1,226 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/store/storetest"
+	"github.com/maloquacious/goobtool/internal/tracing"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) func(string) store.Store {
+		path := filepath.Join(t.TempDir(), "test.db")
+		return func(expected string) store.Store { return New(path, expected) }
+	})
+}
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s := New(filepath.Join(t.TempDir(), "test.db"), "0.1")
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.InitSchema(context.Background(), "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	return s
+}
+
+func TestWithReadTxRejectsWrites(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	err := s.WithReadTx(ctx, func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE items (name TEXT)`)
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected write in read-only transaction to fail")
+	}
+
+	// writes through the read pool fail outside transactions too
+	if _, err := s.ReadDB().ExecContext(ctx, `CREATE TABLE items (name TEXT)`); err == nil {
+		t.Error("expected write on the read pool to fail")
+	}
+}
+
+func TestReadsDoNotWaitForWriter(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	inTx := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- s.WithTx(ctx, func(tx store.Tx) error {
+			if _, err := tx.ExecContext(ctx, `CREATE TABLE items (name TEXT)`); err != nil {
+				return err
+			}
+			close(inTx)
+			<-release
+			return nil
+		})
+	}()
+	<-inTx
+
+	// the writer pool is busy; reads are served by the read pool
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := s.GetSchemaVersion(readCtx); err != nil {
+		t.Errorf("read blocked behind writer: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+}
+
+// attemptsTracer records the goob.tx.attempts attribute of WithTx spans.
+type attemptsTracer struct {
+	tracing.Tracer
+	attempts int64
+}
+
+func (a *attemptsTracer) Start(ctx context.Context, name string, kind tracing.SpanKind, attrs ...tracing.Attr) (context.Context, tracing.Span) {
+	ctx, span := tracing.Noop.Start(ctx, name, kind, attrs...)
+	return ctx, attemptsSpan{Span: span, t: a}
+}
+
+type attemptsSpan struct {
+	tracing.Span
+	t *attemptsTracer
+}
+
+func (s attemptsSpan) SetAttributes(attrs ...tracing.Attr) {
+	for _, a := range attrs {
+		if a.Key == "goob.tx.attempts" {
+			s.t.attempts = a.Value.(int64)
+		}
+	}
+}
+
+func TestWithTxRetriesBusy(t *testing.T) {
+	saved := busyTimeout
+	busyTimeout = time.Millisecond
+	t.Cleanup(func() { busyTimeout = saved })
+
+	s := newTestStore(t)
+	tracer := &attemptsTracer{Tracer: tracing.Noop}
+	s.SetTracer(tracer)
+	ctx := context.Background()
+
+	// another process holds the write lock
+	other, err := sql.Open("sqlite", dsn(s.dbPath, url.Values{"_pragma": {"busy_timeout(1)"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	conn, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	lock := func() {
+		t.Helper()
+		if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+			t.Fatalf("BEGIN IMMEDIATE failed: %v", err)
+		}
+	}
+	unlock := func() error {
+		_, err := conn.ExecContext(ctx, `ROLLBACK`)
+		return err
+	}
+	write := func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS items (name TEXT)`)
+		return err
+	}
+
+	t.Run("gives up", func(t *testing.T) {
+		lock()
+		err := s.WithTx(ctx, write)
+		if err := unlock(); err != nil {
+			t.Fatalf("ROLLBACK failed: %v", err)
+		}
+		if !isBusy(err) {
+			t.Fatalf("WithTx = %v, want SQLITE_BUSY", err)
+		}
+		if tracer.attempts != txMaxAttempts {
+			t.Errorf("attempts = %d, want %d", tracer.attempts, txMaxAttempts)
+		}
+	})
+
+	t.Run("succeeds after release", func(t *testing.T) {
+		lock()
+		// released between the second and third attempts
+		released := make(chan error, 1)
+		time.AfterFunc(20*time.Millisecond, func() { released <- unlock() })
+		err := s.WithTx(ctx, write)
+		if err := <-released; err != nil {
+			t.Fatalf("ROLLBACK failed: %v", err)
+		}
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if tracer.attempts < 2 || tracer.attempts > txMaxAttempts {
+			t.Errorf("attempts = %d, want a retry", tracer.attempts)
+		}
+	})
+
+	t.Run("InitSchema retries", func(t *testing.T) {
+		lock()
+		released := make(chan error, 1)
+		time.AfterFunc(20*time.Millisecond, func() { released <- unlock() })
+		err := s.InitSchema(ctx, "0.2")
+		if err := <-released; err != nil {
+			t.Fatalf("ROLLBACK failed: %v", err)
+		}
+		if err != nil {
+			t.Fatalf("InitSchema failed: %v", err)
+		}
+		if tracer.attempts < 2 {
+			t.Errorf("attempts = %d, want a retry", tracer.attempts)
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	s := newTestStore(t)
+
+	st, err := s.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if st.SchemaVersion != "0.1" || st.SizeBytes == 0 || st.PageSize == 0 || st.LastMigration.IsZero() {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestStatsSchemaErrors(t *testing.T) {
+	ctx := context.Background()
+	s := New(filepath.Join(t.TempDir(), "test.db"), "0.1")
+	if err := s.Open(ctx); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	// no schema yet: zero values, no error
+	st, err := s.Stats(ctx)
+	if err != nil || st.SchemaVersion != "" || !st.LastMigration.IsZero() {
+		t.Fatalf("Stats on empty store = %+v, %v", st, err)
+	}
+
+	// any other schema query failure is reported
+	if _, err := s.DB().ExecContext(ctx, `CREATE TABLE schema_migrations (version TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stats(ctx); err == nil {
+		t.Error("Stats ignored a broken schema_migrations table")
+	}
+}
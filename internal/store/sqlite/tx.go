@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/store"
+	"github.com/maloquacious/goobtool/internal/tracing"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Busy retry policy for WithTx. busy_timeout already waits inside SQLite;
+// these retries cover busy errors it gives up on, such as another process
+// holding the write lock for longer than the timeout.
+const (
+	txMaxAttempts  = 5
+	txRetryBackoff = 10 * time.Millisecond
+)
+
+// isBusy reports whether err is SQLITE_BUSY or one of its extended codes.
+func isBusy(err error) bool {
+	var se *msqlite.Error
+	return errors.As(err, &se) && se.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// WithTx runs fn in a read-write transaction on the writer pool, retrying
+// on SQLITE_BUSY. Writer transactions begin IMMEDIATE, so lock contention
+// with other processes surfaces at BEGIN rather than mid-transaction.
+func (s *SQLiteStore) WithTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	if s.db == nil {
+		return fmt.Errorf("database not opened")
+	}
+
+	ctx, span := s.startSpan(ctx, "WithTx", tracing.Bool("db.transaction", true))
+	attempts := 0
+	defer func() {
+		span.SetAttributes(tracing.Int("goob.tx.attempts", attempts))
+		span.RecordError(err)
+		span.End()
+	}()
+
+	backoff := txRetryBackoff
+	for {
+		attempts++
+		err = s.runTx(ctx, fn)
+		if !isBusy(err) || attempts >= txMaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("transaction retry abandoned: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// runTx is a single WithTx attempt.
+func (s *SQLiteStore) runTx(ctx context.Context, fn func(store.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// WithReadTx runs fn in a transaction on the read-only pool. Its
+// connections are opened with mode=ro and query_only, so writes fail.
+func (s *SQLiteStore) WithReadTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	if s.readDB == nil {
+		return fmt.Errorf("database not opened")
+	}
+
+	ctx, span := s.startSpan(ctx, "WithReadTx", tracing.Bool("db.transaction", true))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	tx, err := s.readDB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ApplyMigration runs fn and records version in schema_migrations in one
+// WithTx transaction, so a failed migration leaves no trace.
+func (s *SQLiteStore) ApplyMigration(ctx context.Context, version string, fn func(store.Tx) error) error {
+	return s.WithTx(ctx, func(tx store.Tx) error {
+		if err := fn(tx); err != nil {
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, strftime('%s', 'now'))`, version); err != nil {
+			return fmt.Errorf("failed to record schema version %s: %w", version, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,400 @@
+// Package storetest is the conformance suite every store.Store backend must
+// pass. A backend's tests call Run with a Factory for its datastore:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) func(string) store.Store {
+//			path := filepath.Join(t.TempDir(), "test.db")
+//			return func(expected string) store.Store { return sqlite.New(path, expected) }
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/store"
+)
+
+// Factory prepares a fresh, empty datastore for t and returns a constructor
+// for unopened stores over it. Every store the constructor returns shares
+// that datastore; stores from different Factory calls never do. Cleanup of
+// the datastore should be registered on t.
+type Factory func(t *testing.T) func(expectedSchema string) store.Store
+
+// runaway never finishes on its own; it works on SQLite and PostgreSQL.
+const runaway = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT MAX(x) FROM c`
+
+// Run runs the conformance suite as subtests of t.
+func Run(t *testing.T, factory Factory) {
+	t.Run("StateTransitions", func(t *testing.T) { testStateTransitions(t, factory) })
+	t.Run("VersionReads", func(t *testing.T) { testVersionReads(t, factory) })
+	t.Run("Transactions", func(t *testing.T) { testTransactions(t, factory) })
+	t.Run("Migrations", func(t *testing.T) { testMigrations(t, factory) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, factory) })
+	t.Run("Cancellation", func(t *testing.T) { testCancellation(t, factory) })
+	t.Run("Close", func(t *testing.T) { testClose(t, factory) })
+}
+
+// open returns an opened store and closes it when the test ends.
+func open(t *testing.T, newStore func(string) store.Store, expected string) store.Store {
+	t.Helper()
+	s := newStore(expected)
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// ready returns an opened store with the schema initialized at "0.1".
+func ready(t *testing.T, factory Factory) store.Store {
+	t.Helper()
+	s := open(t, factory(t), "0.1")
+	if err := s.InitSchema(context.Background(), "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	return s
+}
+
+func checkState(t *testing.T, s store.Store, want store.StoreState) {
+	t.Helper()
+	got, err := s.CheckState(context.Background())
+	if err != nil {
+		t.Fatalf("CheckState failed: %v", err)
+	}
+	if got != want {
+		t.Fatalf("CheckState = %v, want %v", got, want)
+	}
+}
+
+func testStateTransitions(t *testing.T, factory Factory) {
+	newStore := factory(t)
+	ctx := context.Background()
+
+	// missing: nothing is usable before Open
+	s := newStore("0.1")
+	state, err := s.CheckState(ctx)
+	if err == nil || state != store.StateMissing {
+		t.Fatalf("CheckState before Open = %v, %v; want StateMissing and an error", state, err)
+	}
+
+	if err := s.Open(ctx); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	checkState(t, s, store.StateUninitialized)
+
+	if err := s.InitSchema(ctx, "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	checkState(t, s, store.StateReady)
+
+	// the same datastore seen by builds expecting other versions
+	checkState(t, open(t, newStore, "0.2"), store.StateNeedsUpgrade)
+	checkState(t, open(t, newStore, "0.0.9"), store.StateTooNew)
+	checkState(t, open(t, newStore, "0.1.0"), store.StateReady)
+	checkState(t, s, store.StateReady)
+}
+
+func testVersionReads(t *testing.T, factory Factory) {
+	newStore := factory(t)
+	ctx := context.Background()
+
+	s := open(t, newStore, "0.1")
+	if err := s.InitSchema(ctx, "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	for _, st := range []store.Store{s, open(t, newStore, "0.2")} {
+		v, err := st.GetSchemaVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetSchemaVersion failed: %v", err)
+		}
+		if v != "0.1" {
+			t.Errorf("GetSchemaVersion = %q, want %q", v, "0.1")
+		}
+	}
+
+	// migrations applied in the same second order by semver, not by text
+	err := s.WithTx(ctx, func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ('0.10', 4102444800), ('0.2', 4102444800)`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("inserting versions failed: %v", err)
+	}
+	v, err := s.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("GetSchemaVersion failed: %v", err)
+	}
+	if v != "0.10" {
+		t.Errorf("GetSchemaVersion = %q, want %q", v, "0.10")
+	}
+	checkState(t, s, store.StateTooNew)
+	checkState(t, open(t, newStore, "0.10"), store.StateReady)
+}
+
+func testMigrations(t *testing.T, factory Factory) {
+	s := ready(t, factory)
+	ctx := context.Background()
+	exec := func(query string) func(context.Context, store.Tx) error {
+		return func(ctx context.Context, tx store.Tx) error {
+			_, err := tx.ExecContext(ctx, query)
+			return err
+		}
+	}
+	migrations := []store.Migration{
+		{Version: "0.3", Up: exec(`ALTER TABLE widgets ADD COLUMN size INTEGER`)},
+		{Version: "0.2", Up: exec(`CREATE TABLE widgets (id INTEGER PRIMARY KEY)`)},
+		{Version: "0.4", Up: exec(`CREATE TABLE gadgets (id INTEGER PRIMARY KEY)`)},
+	}
+
+	if _, err := store.Migrate(ctx, s, migrations, "0.3.1"); err == nil {
+		t.Error("Migrate to a version no migration reaches succeeded")
+	}
+	applied, err := store.Migrate(ctx, s, migrations, "0.3")
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0] != "0.2" || applied[1] != "0.3" {
+		t.Errorf("applied %v, want [0.2 0.3]", applied)
+	}
+	if v, _ := s.GetSchemaVersion(ctx); v != "0.3" {
+		t.Errorf("schema version = %q, want 0.3", v)
+	}
+	if err := s.WithTx(ctx, func(tx store.Tx) error { return exec(`INSERT INTO widgets (id, size) VALUES (1, 2)`)(ctx, tx) }); err != nil {
+		t.Errorf("migrated table is unusable: %v", err)
+	}
+	if applied, err := store.Migrate(ctx, s, migrations, "0.3"); err != nil || len(applied) != 0 {
+		t.Errorf("Migrate at the target = %v, %v; want nothing applied", applied, err)
+	}
+	if _, err := store.Migrate(ctx, s, migrations, "0.2"); err == nil {
+		t.Error("Migrate to an older version succeeded")
+	}
+
+	// a failing migration is rolled back along with its version record
+	broken := append(migrations, store.Migration{Version: "0.5", Up: func(ctx context.Context, tx store.Tx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE doomed (id INTEGER)`); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+	applied, err = store.Migrate(ctx, s, broken, "0.5")
+	if err == nil {
+		t.Fatal("Migrate with a failing migration succeeded")
+	}
+	if len(applied) != 1 || applied[0] != "0.4" {
+		t.Errorf("applied %v before the failure, want [0.4]", applied)
+	}
+	if v, _ := s.GetSchemaVersion(ctx); v != "0.4" {
+		t.Errorf("schema version after a failed migration = %q, want 0.4", v)
+	}
+	if err := s.WithReadTx(ctx, func(tx store.Tx) error { return exec(`SELECT 1 FROM doomed`)(ctx, tx) }); err == nil {
+		t.Error("the failed migration's table exists")
+	}
+}
+
+func testTransactions(t *testing.T, factory Factory) {
+	s := ready(t, factory)
+	ctx := context.Background()
+
+	err := s.WithTx(ctx, func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE storetest_items (name TEXT NOT NULL)`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	err = s.WithTx(ctx, func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO storetest_items (name) VALUES ('kept')`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx insert failed: %v", err)
+	}
+
+	// an error from fn is returned as is and rolls back
+	errBoom := errors.New("boom")
+	err = s.WithTx(ctx, func(tx store.Tx) error {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO storetest_items (name) VALUES ('lost')`); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithTx returned %v, want the callback error", err)
+	}
+
+	var names []string
+	err = s.WithReadTx(ctx, func(tx store.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT name FROM storetest_items ORDER BY name`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		t.Fatalf("WithReadTx failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "kept" {
+		t.Errorf("rows = %v, want [kept]", names)
+	}
+
+	err = s.WithReadTx(ctx, func(tx store.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO storetest_items (name) VALUES ('sneaky')`)
+		return err
+	})
+	if err == nil {
+		t.Error("write in WithReadTx succeeded")
+	}
+}
+
+func testConcurrency(t *testing.T, factory Factory) {
+	s := ready(t, factory)
+	ctx := context.Background()
+
+	err := s.WithTx(ctx, func(tx store.Tx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE storetest_counter (n INTEGER NOT NULL)`); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO storetest_counter (n) VALUES (0)`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	const workers, increments = 8, 20
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range increments {
+				err := s.WithTx(ctx, func(tx store.Tx) error {
+					_, err := tx.ExecContext(ctx, `UPDATE storetest_counter SET n = n + 1`)
+					return err
+				})
+				if err != nil {
+					t.Errorf("increment failed: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range increments {
+				if _, err := s.CheckState(ctx); err != nil {
+					t.Errorf("CheckState failed: %v", err)
+					return
+				}
+				err := s.WithReadTx(ctx, func(tx store.Tx) error {
+					var n int
+					return tx.QueryRowContext(ctx, `SELECT n FROM storetest_counter`).Scan(&n)
+				})
+				if err != nil {
+					t.Errorf("read failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	err = s.WithReadTx(ctx, func(tx store.Tx) error {
+		return tx.QueryRowContext(ctx, `SELECT n FROM storetest_counter`).Scan(&n)
+	})
+	if err != nil {
+		t.Fatalf("final read failed: %v", err)
+	}
+	if n != workers*increments {
+		t.Errorf("counter = %d, want %d (lost updates)", n, workers*increments)
+	}
+}
+
+func testCancellation(t *testing.T, factory Factory) {
+	s := ready(t, factory)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.CheckState(cancelled); err == nil {
+		t.Error("CheckState ignored a cancelled context")
+	}
+	if _, err := s.GetSchemaVersion(cancelled); err == nil {
+		t.Error("GetSchemaVersion ignored a cancelled context")
+	}
+	for name, run := range map[string]func(context.Context, func(store.Tx) error) error{
+		"WithTx":     s.WithTx,
+		"WithReadTx": s.WithReadTx,
+	} {
+		called := false
+		err := run(cancelled, func(store.Tx) error { called = true; return nil })
+		if err == nil || called {
+			t.Errorf("%s with a cancelled context: err=%v, callback ran=%v", name, err, called)
+		}
+	}
+
+	// a running query stops at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := s.WithReadTx(ctx, func(tx store.Tx) error {
+		var n int64
+		return tx.QueryRowContext(ctx, runaway).Scan(&n)
+	})
+	if err == nil {
+		t.Fatal("runaway query was not interrupted")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("runaway query ran %s past a 50ms deadline", d)
+	}
+
+	// and the store is still usable afterwards
+	checkState(t, s, store.StateReady)
+}
+
+func testClose(t *testing.T, factory Factory) {
+	newStore := factory(t)
+	ctx := context.Background()
+
+	if err := newStore("0.1").Close(); err != nil {
+		t.Errorf("Close before Open failed: %v", err)
+	}
+
+	s := newStore("0.1")
+	if err := s.Open(ctx); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := s.InitSchema(ctx, "0.1"); err != nil {
+		t.Fatalf("InitSchema failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+
+	if _, err := s.CheckState(ctx); err == nil {
+		t.Error("CheckState after Close succeeded")
+	}
+	if err := s.WithTx(ctx, func(store.Tx) error { return nil }); err == nil {
+		t.Error("WithTx after Close succeeded")
+	}
+
+	// data written before Close is there for the next store
+	checkState(t, open(t, newStore, "0.1"), store.StateReady)
+}
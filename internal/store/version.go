@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maloquacious/semver"
+)
+
+// ParseVersion parses a schema version such as "0.1", "1.2.3" or
+// "1.0.0-rc.1+build". The patch number may be omitted and defaults to 0.
+func ParseVersion(s string) (semver.Version, error) {
+	var v semver.Version
+	rest := s
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		rest, v.Build = rest[:i], rest[i+1:]
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		rest, v.PreRelease = rest[:i], rest[i+1:]
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return semver.Version{}, fmt.Errorf("invalid schema version %q: want MAJOR.MINOR[.PATCH]", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return semver.Version{}, fmt.Errorf("invalid schema version %q: bad number %q", s, p)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// LatestVersion returns the highest of versions by semver precedence.
+// Unparseable entries are ignored; if none parse, the last entry is
+// returned, so callers should list versions in the order they were applied.
+func LatestVersion(versions []string) string {
+	latest, best := "", semver.Version{}
+	found := false
+	for _, s := range versions {
+		v, err := ParseVersion(s)
+		if err != nil {
+			continue
+		}
+		if !found || best.Less(v) {
+			latest, best, found = s, v, true
+		}
+	}
+	if !found && len(versions) > 0 {
+		return versions[len(versions)-1]
+	}
+	return latest
+}
+
+// CompareVersions returns the state of a datastore whose schema is actual
+// for a binary that expects expected. Versions of equal precedence are
+// ready; a version that does not parse is a plain mismatch.
+func CompareVersions(actual, expected string) StoreState {
+	a, err := ParseVersion(actual)
+	if err != nil {
+		return StateVersionMismatch
+	}
+	e, err := ParseVersion(expected)
+	if err != nil {
+		return StateVersionMismatch
+	}
+	switch c := a.Compare(e); {
+	case c < 0:
+		return StateNeedsUpgrade
+	case c > 0:
+		return StateTooNew
+	}
+	return StateReady
+}
@@ -0,0 +1,69 @@
+package store
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "0.1", want: "0.1.0"},
+		{in: "1.2.3", want: "1.2.3"},
+		{in: "1.0.0-rc.1+abc", want: "1.0.0-rc.1+abc"},
+		{in: "1", wantErr: true},
+		{in: "1.2.3.4", wantErr: true},
+		{in: "1.x", wantErr: true},
+		{in: "01.2", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && v.String() != tt.want {
+			t.Errorf("ParseVersion(%q) = %q, want %q", tt.in, v.String(), tt.want)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{in: nil, want: ""},
+		{in: []string{"0.2", "0.10", "0.9"}, want: "0.10"},
+		{in: []string{"1.0.0", "1.0.0-rc.1"}, want: "1.0.0"},
+		{in: []string{"0.1", "legacy"}, want: "0.1"},
+		{in: []string{"legacy", "other"}, want: "other"},
+	}
+	for _, tt := range tests {
+		if got := LatestVersion(tt.in); got != tt.want {
+			t.Errorf("LatestVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		actual, expected string
+		want             StoreState
+	}{
+		{"0.1", "0.1", StateReady},
+		{"0.1", "0.1.0", StateReady},
+		{"0.1", "0.2", StateNeedsUpgrade},
+		{"0.9", "0.10", StateNeedsUpgrade},
+		{"1.0.0-rc.1", "1.0.0", StateNeedsUpgrade},
+		{"0.2", "0.1", StateTooNew},
+		{"1.0", "0.9.9", StateTooNew},
+		{"legacy", "0.1", StateVersionMismatch},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.actual, tt.expected); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %v, want %v", tt.actual, tt.expected, got, tt.want)
+		}
+	}
+}
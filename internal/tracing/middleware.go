@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+	"github.com/maloquacious/goobtool/internal/recorder"
+)
+
+// Middleware starts a server span for every request served by mux,
+// continuing any trace received in the traceparent header, and adds
+// trace_id and span_id to the request's log fields. It should run inside
+// requestid.Middleware so access logs carry both IDs.
+//
+// Spans are named after the method and the matched ServeMux route (not the
+// raw path) to keep span names bounded; unmatched requests use the method
+// alone.
+func Middleware(t Tracer, listener string, mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := r.Method
+			attrs := []Attr{
+				String("http.request.method", r.Method),
+				String("url.path", r.URL.Path),
+				String("server.listener", listener),
+				String("user_agent.original", r.UserAgent()),
+			}
+			if route := route(mux, r); route != "" {
+				name += " " + route
+				attrs = append(attrs, String("http.route", route))
+			}
+			ctx := Extract(r.Context(), r.Header)
+			ctx, span := t.Start(ctx, name, KindServer, attrs...)
+			defer span.End()
+
+			if sc := span.SpanContext(); sc.IsValid() {
+				ctx = logger.ContextWith(ctx, "trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String())
+			} else if parent, ok := parentFromContext(ctx); ok {
+				// not recording, but keep the caller's trace ID in our logs
+				ctx = logger.ContextWith(ctx, "trace_id", parent.TraceID.String())
+			}
+			rec := recorder.Wrap(w)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			code := rec.Status()
+			span.SetAttributes(Int("http.response.status_code", code))
+			if code >= 500 {
+				span.RecordError(httpError(code))
+			}
+		})
+	}
+}
+
+type httpError int
+
+func (e httpError) Error() string { return http.StatusText(int(e)) }
+
+// route returns the path of the mux pattern matching r, without any
+// method prefix, or "" when nothing matches.
+func route(mux *http.ServeMux, r *http.Request) string {
+	if mux == nil {
+		return ""
+	}
+	_, pattern := mux.Handler(r)
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+// SpanData is a finished span handed to an Exporter.
+type SpanData struct {
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Name         string
+	Kind         SpanKind
+	Start        time.Time
+	End          time.Time
+	Attrs        []Attr
+	Err          string // error message if RecordError was called
+}
+
+// Exporter sends finished spans to a backend.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Options configures NewTracer. Zero values select the defaults.
+type Options struct {
+	BatchSize     int           // spans per export call (default 512)
+	QueueSize     int           // spans buffered before dropping (default 2048)
+	FlushInterval time.Duration // max time a span waits for export (default 5s)
+	Logger        logger.Logger // export failures are logged here
+}
+
+// RecordingTracer creates real spans and exports sampled ones in batches.
+type RecordingTracer struct {
+	exporter Exporter
+	log      logger.Logger
+	queue    chan SpanData
+	batch    int
+	interval time.Duration
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewTracer creates a RecordingTracer exporting to exp and starts its
+// background batcher. Call Shutdown to flush and stop it.
+func NewTracer(exp Exporter, opts Options) *RecordingTracer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = logger.Default
+	}
+	t := &RecordingTracer{
+		exporter: exp,
+		log:      opts.Logger,
+		queue:    make(chan SpanData, opts.QueueSize),
+		batch:    opts.BatchSize,
+		interval: opts.FlushInterval,
+		done:     make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+// Start implements Tracer. Spans without a parent start a new sampled trace;
+// child spans inherit the parent's trace ID and sampling decision.
+func (t *RecordingTracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, Span) {
+	s := &recordingSpan{
+		tracer: t,
+		data: SpanData{
+			Name:  name,
+			Kind:  kind,
+			Start: time.Now(),
+			Attrs: append([]Attr(nil), attrs...),
+		},
+	}
+	if parent, ok := parentFromContext(ctx); ok {
+		s.data.SpanContext = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		s.data.ParentSpanID = parent.SpanID
+	} else {
+		s.data.SpanContext = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// Shutdown implements Tracer. It exports queued spans, then shuts down the exporter.
+func (t *RecordingTracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.done) })
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+func (t *RecordingTracer) enqueue(sd SpanData) {
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	select {
+	case t.queue <- sd:
+	default:
+		t.log.Warn("tracing: span queue full, dropping span name=%s", sd.Name)
+	}
+}
+
+func (t *RecordingTracer) run() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	var pending []SpanData
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := t.exporter.ExportSpans(ctx, pending); err != nil {
+			t.log.Warn("tracing: export failed spans=%d: %v", len(pending), err)
+		}
+		pending = nil
+	}
+
+	for {
+		select {
+		case sd := <-t.queue:
+			pending = append(pending, sd)
+			if len(pending) >= t.batch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.done:
+			for {
+				select {
+				case sd := <-t.queue:
+					pending = append(pending, sd)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// recordingSpan accumulates span data until End.
+type recordingSpan struct {
+	tracer *RecordingTracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err.Error()
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	sd := s.data
+	s.mu.Unlock()
+
+	if sd.SpanContext.Sampled {
+		s.tracer.enqueue(sd)
+	}
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/maloquacious/goobtool/internal/logger"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(valid)
+	if err != nil {
+		t.Fatalf("ParseTraceparent(valid): %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("parsed %+v", sc)
+	}
+	if got := sc.Traceparent(); got != valid {
+		t.Errorf("round trip: got %q, want %q", got, valid)
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("ParseTraceparent(%q): expected error", bad)
+		}
+	}
+}
+
+// memExporter collects exported spans.
+type memExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (m *memExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, spans...)
+	return nil
+}
+
+func (m *memExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestMiddlewarePropagation(t *testing.T) {
+	exp := &memExporter{}
+	tr := NewTracer(exp, Options{})
+
+	var fields []any
+	var child SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		fields = logger.ContextFields(r.Context())
+		_, span := tr.Start(r.Context(), "sqlite GetSchemaVersion", KindClient)
+		child = span.SpanContext()
+		span.RecordError(errors.New("boom"))
+		span.End()
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := Middleware(tr, "public", mux)(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if len(exp.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(exp.spans))
+	}
+	db, server := exp.spans[0], exp.spans[1]
+
+	if server.Name != "GET /ready" {
+		t.Errorf("server span name = %q, want %q", server.Name, "GET /ready")
+	}
+	if server.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span did not continue remote trace: %s", server.SpanContext.TraceID)
+	}
+	if server.ParentSpanID.String() != "00f067aa0ba902b7" || server.Kind != KindServer {
+		t.Errorf("server span parent/kind = %s/%d", server.ParentSpanID, server.Kind)
+	}
+	if db.ParentSpanID != server.SpanContext.SpanID || db.SpanContext != child || db.Err != "boom" {
+		t.Errorf("child span not linked to server span: %+v", db)
+	}
+	if len(fields) != 4 || fields[0] != "trace_id" || fields[1] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("log fields = %v", fields)
+	}
+}
+
+func TestMiddlewareSpanNames(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/public/", func(w http.ResponseWriter, r *http.Request) {})
+
+	exp := &memExporter{}
+	tr := NewTracer(exp, Options{})
+	h := Middleware(tr, "public", mux)(mux)
+	for _, target := range []string{"/users/1", "/users/2", "/public/css/site.css", "/nope/123"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	var names []string
+	for _, sd := range exp.spans {
+		names = append(names, sd.Name)
+	}
+	want := []string{"GET /users/{id}", "GET /users/{id}", "GET /public/", "GET"}
+	if !slices.Equal(names, want) {
+		t.Errorf("span names = %q, want %q", names, want)
+	}
+}
+
+func TestNoopKeepsRemoteTraceID(t *testing.T) {
+	var fields []any
+	var out http.Header = http.Header{}
+	h := Middleware(Noop, "public", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields = logger.ContextFields(r.Context())
+		Inject(r.Context(), out)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(fields) != 2 || fields[1] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("log fields = %v", fields)
+	}
+	if got := out.Get(TraceparentHeader); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00" {
+		t.Errorf("Inject = %q", got)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(srv.URL+"/v1/traces", "goobtool")
+	tr := NewTracer(exp, Options{})
+	_, span := tr.Start(context.Background(), "root", KindInternal, String("k", "v"), Int("n", 3))
+	span.End()
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	rs := got["resourceSpans"].([]any)[0].(map[string]any)
+	res := rs["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if res["key"] != "service.name" || res["value"].(map[string]any)["stringValue"] != "goobtool" {
+		t.Errorf("resource attributes = %v", res)
+	}
+	s := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	if s["name"] != "root" || len(s["traceId"].(string)) != 32 || len(s["spanId"].(string)) != 16 {
+		t.Errorf("span = %v", s)
+	}
+	attrs := s["attributes"].([]any)
+	if n := attrs[1].(map[string]any)["value"].(map[string]any)["intValue"]; n != "3" {
+		t.Errorf("int attribute = %v, want \"3\"", n)
+	}
+}
@@ -0,0 +1,173 @@
+//go:build ignore
+
+// gen_vendor fetches the frontend libraries pinned in vendor.json into
+// vendor/ under content-hashed names, next to gzip copies for the static
+// handler, and records each copy's path and Subresource Integrity value
+// back in vendor.json. Run it with
+//
+//	go generate ./public
+//
+// Libraries already on disk with the recorded integrity are not fetched
+// again. A download whose integrity differs from the recorded value is an
+// error unless -update is given, e.g. after bumping a version.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type asset struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Source    string `json:"source"`
+	Path      string `json:"path,omitempty"`
+	Integrity string `json:"integrity,omitempty"`
+}
+
+func main() {
+	update := flag.Bool("update", false, "accept upstream content that differs from the recorded integrity")
+	flag.Parse()
+	if err := run(*update); err != nil {
+		fmt.Fprintf(os.Stderr, "gen_vendor: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(update bool) error {
+	data, err := os.ReadFile("vendor.json")
+	if err != nil {
+		return fmt.Errorf("failed to read vendor.json: %w", err)
+	}
+	var assets []asset
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return fmt.Errorf("failed to parse vendor.json: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	keep := map[string]bool{"README.md": true}
+	for i := range assets {
+		a := &assets[i]
+		if a.Path != "" && !update {
+			if data, err := os.ReadFile(filepath.FromSlash(a.Path)); err == nil && integrity(data) == a.Integrity {
+				if err := writeGzip(a.Path, data); err != nil {
+					return err
+				}
+				keep[path.Base(a.Path)] = true
+				fmt.Printf("%-12s %s (up to date)\n", a.Name, a.Path)
+				continue
+			}
+		}
+
+		data, err := fetch(client, a.Source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Name, err)
+		}
+		sri := integrity(data)
+		if a.Integrity != "" && sri != a.Integrity && !update {
+			return fmt.Errorf("%s: %s has integrity %s, want %s (rerun with -update to accept it)", a.Name, a.Source, sri, a.Integrity)
+		}
+
+		a.Path = "vendor/" + hashedName(*a, data)
+		a.Integrity = sri
+		if err := os.WriteFile(filepath.FromSlash(a.Path), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", a.Path, err)
+		}
+		if err := writeGzip(a.Path, data); err != nil {
+			return err
+		}
+		keep[path.Base(a.Path)] = true
+		fmt.Printf("%-12s %s (fetched)\n", a.Name, a.Path)
+	}
+
+	// drop copies of versions that are no longer pinned
+	entries, err := os.ReadDir("vendor")
+	if err != nil {
+		return fmt.Errorf("failed to read vendor: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && !keep[e.Name()] && !keep[strings.TrimSuffix(e.Name(), ".gz")] {
+			if err := os.Remove(filepath.Join("vendor", e.Name())); err != nil {
+				return fmt.Errorf("failed to remove stale %s: %w", e.Name(), err)
+			}
+			fmt.Printf("%-12s vendor/%s (removed)\n", "", e.Name())
+		}
+	}
+
+	out, err := json.MarshalIndent(assets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile("vendor.json", append(out, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write vendor.json: %w", err)
+	}
+	return nil
+}
+
+func fetch(client *http.Client, src string) ([]byte, error) {
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", src, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	return data, nil
+}
+
+// writeGzip writes the precompressed copy the static handler serves to
+// clients that accept gzip.
+func writeGzip(name string, data []byte) error {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.FromSlash(name)+".gz", buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s.gz: %w", name, err)
+	}
+	return nil
+}
+
+// integrity returns the SRI value browsers check the file against.
+func integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// hashedName names a copy after the library, its version and its content,
+// e.g. "htmx-2.0.3.1a2b3c4d.js", so a new version or upstream change gets a
+// new URL.
+func hashedName(a asset, data []byte) string {
+	ext := ".js"
+	if u, err := url.Parse(a.Source); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	sum := sha512.Sum384(data)
+	return fmt.Sprintf("%s-%s.%s%s", strings.TrimSuffix(a.Name, ext), a.Version, hex.EncodeToString(sum[:4]), ext)
+}
@@ -0,0 +1,13 @@
+// Package public embeds the default public assets so a deployed binary can
+// serve them without a ./public directory. The --public directory is
+// overlaid on top of these at runtime.
+package public
+
+import "embed"
+
+//go:generate go run gen_vendor.go
+
+// FS holds the default assets, with paths relative to this directory.
+//
+//go:embed vendor
+var FS embed.FS
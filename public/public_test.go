@@ -0,0 +1,54 @@
+package public
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// TestVendor checks that pins name exact versions and that every pinned
+// library is vendored and embedded with the recorded integrity.
+func TestVendor(t *testing.T) {
+	for _, a := range VendorAssets() {
+		if strings.ContainsAny(a.Version, "x*^~") || !strings.Contains(a.Source, a.Version) {
+			t.Errorf("%s: version %q is not pinned in source %q", a.Name, a.Version, a.Source)
+		}
+		if !a.Vendored() {
+			t.Errorf("%s is not vendored; run go generate ./public and commit the result", a.Name)
+			continue
+		}
+		data, err := fs.ReadFile(FS, a.Path)
+		if err != nil {
+			t.Errorf("%s: %v", a.Name, err)
+			continue
+		}
+		sum := sha512.Sum384(data)
+		if got := "sha384-" + base64.StdEncoding.EncodeToString(sum[:]); got != a.Integrity {
+			t.Errorf("%s: %s has integrity %s, vendor.json records %s", a.Name, a.Path, got, a.Integrity)
+		}
+		if _, err := fs.Stat(FS, a.Path+".gz"); err != nil {
+			t.Errorf("%s: no gzip copy: %v", a.Name, err)
+		}
+	}
+	if err := CheckVendor(); err != nil {
+		t.Error(err)
+	}
+	if missing := Unvendored(); len(missing) > 0 {
+		t.Errorf("not vendored: %v", missing)
+	}
+}
+
+func TestParseVendor(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing source": `[{"name": "htmx", "version": "2.0.3"}]`,
+		"duplicate":      `[{"name": "htmx", "version": "2.0.3", "source": "s"}, {"name": "htmx", "version": "2.0.4", "source": "s"}]`,
+		"bad path":       `[{"name": "htmx", "version": "2.0.3", "source": "s", "path": "../htmx.js", "integrity": "sha384-x"}]`,
+		"no integrity":   `[{"name": "htmx", "version": "2.0.3", "source": "s", "path": "vendor/htmx.js"}]`,
+	} {
+		if _, err := parseVendor([]byte(data)); err == nil {
+			t.Errorf("%s: parseVendor succeeded", name)
+		}
+	}
+}
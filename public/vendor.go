@@ -0,0 +1,122 @@
+package public
+
+import (
+	"crypto/sha512"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Asset is a pinned frontend library from vendor.json.
+type Asset struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Source is the upstream URL the pinned copy is fetched from.
+	Source string `json:"source"`
+	// Path is the content-hashed copy in FS, e.g. "vendor/htmx.min.1a2b3c4d.js".
+	// It is empty until `go generate ./public` has fetched the library.
+	Path string `json:"path,omitempty"`
+	// Integrity is the Subresource Integrity value for the pinned copy.
+	Integrity string `json:"integrity,omitempty"`
+}
+
+// Vendored reports whether vendor.json records a copy of the library.
+// CheckVendor verifies that the copy is embedded.
+func (a Asset) Vendored() bool {
+	return a.Path != ""
+}
+
+//go:embed vendor.json
+var vendorJSON []byte
+
+var vendorAssets = mustParseVendor(vendorJSON)
+
+func mustParseVendor(data []byte) []Asset {
+	assets, err := parseVendor(data)
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
+
+func parseVendor(data []byte) ([]Asset, error) {
+	var assets []Asset
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, fmt.Errorf("failed to parse vendor.json: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, a := range assets {
+		if a.Name == "" || a.Version == "" || a.Source == "" {
+			return nil, fmt.Errorf("vendor.json: %q needs a name, version and source", a.Name)
+		}
+		if seen[a.Name] {
+			return nil, fmt.Errorf("vendor.json: duplicate library %q", a.Name)
+		}
+		seen[a.Name] = true
+		if a.Vendored() && (!strings.HasPrefix(a.Path, "vendor/") || !strings.HasPrefix(a.Integrity, "sha384-")) {
+			return nil, fmt.Errorf("vendor.json: %q has path %q and integrity %q", a.Name, a.Path, a.Integrity)
+		}
+	}
+	return assets, nil
+}
+
+// Vendor returns the pinned library called name.
+func Vendor(name string) (Asset, bool) {
+	for _, a := range vendorAssets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// VendorAssets returns every pinned library.
+func VendorAssets() []Asset {
+	return append([]Asset(nil), vendorAssets...)
+}
+
+// Unvendored returns the names of pinned libraries that `go generate
+// ./public` has not fetched yet.
+func Unvendored() []string {
+	var names []string
+	for _, a := range vendorAssets {
+		if !a.Vendored() {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// CheckVendor reports every vendored library that has no embedded copy in
+// FS matching its recorded integrity. Libraries not vendored yet are listed
+// by Unvendored instead.
+func CheckVendor() error {
+	var errs []error
+	for _, a := range vendorAssets {
+		if !a.Vendored() {
+			continue
+		}
+		data, err := fs.ReadFile(FS, a.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.Name, err))
+			continue
+		}
+		if got := integrity(data); got != a.Integrity {
+			errs = append(errs, fmt.Errorf("%s: %s has integrity %s, vendor.json records %s", a.Name, a.Path, got, a.Integrity))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("vendored frontend libraries do not match vendor.json, run go generate ./public: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// integrity returns the sha384 Subresource Integrity value of data.
+func integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
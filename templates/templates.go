@@ -0,0 +1,11 @@
+// Package templates embeds the server-rendered HTML templates: a base
+// layout, shared partials and one template per page (see internal/render).
+// Production builds render these; `serve --dev` reloads them from disk.
+package templates
+
+import "embed"
+
+// FS holds the templates, with paths relative to this directory.
+//
+//go:embed layouts partials pages
+var FS embed.FS